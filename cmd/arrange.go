@@ -0,0 +1,178 @@
+// cmd/arrange.go
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys of the --sort flag.
+const (
+	sortCode    = "code"
+	sortComment = "comment"
+	sortBlank   = "blank"
+	sortFiles   = "files"
+	sortName    = "name"
+	sortBytes   = "bytes"
+)
+
+// sortKeys lists the values accepted by the --sort flag.
+var sortKeys = []string{sortCode, sortComment, sortBlank, sortFiles, sortName, sortBytes}
+
+// validateArrangement is a function that checks the values of the --sort, --top, --min-lines and --by flags.
+func validateArrangement() error {
+	if sortKey != "" {
+		valid := false
+		for _, key := range sortKeys {
+			if key == sortKey {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown sort key %q, expected one of %s", sortKey, strings.Join(sortKeys, ", "))
+		}
+	}
+	if topFiles < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+	if minLines < 0 {
+		return fmt.Errorf("--min-lines must not be negative")
+	}
+	valid := false
+	for _, by := range granularities {
+		if by == reportBy {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("unknown granularity %q, expected one of %s", reportBy, strings.Join(granularities, ", "))
+	}
+	return nil
+}
+
+// arrange is a function that selects and orders the entries of a table following the --sort, --reverse, --top and --min-lines flags.
+// It takes the number of entries, a function returning the name and the counts of an entry, the sort key used when --sort is not given
+// ("" keeps the order of the entries) and whether --top applies to the table.
+// Numeric keys sort the largest first and the name key sorts alphabetically, --reverse reverses the order, --min-lines hides the entries
+// with fewer lines of code, and --top keeps the first entries. It returns the indices of the selected entries, in order.
+func arrange(n int, entry func(i int) (string, languageSummary), defaultKey string, limit bool) []int {
+	key := defaultKey
+	if sortKey != "" {
+		key = sortKey
+	}
+
+	// Hide the entries with too few lines of code
+	var indices []int
+	for i := 0; i < n; i++ {
+		if _, summary := entry(i); summary.Code >= minLines {
+			indices = append(indices, i)
+		}
+	}
+
+	// Order the entries, stably so that ties keep their original order
+	if key != "" {
+		value := func(summary languageSummary) int64 {
+			switch key {
+			case sortComment:
+				return int64(summary.Comment)
+			case sortBlank:
+				return int64(summary.Blank)
+			case sortFiles:
+				return int64(summary.Files)
+			case sortBytes:
+				return summary.Bytes
+			default:
+				return int64(summary.Code)
+			}
+		}
+		sort.SliceStable(indices, func(a, b int) bool {
+			nameA, summaryA := entry(indices[a])
+			nameB, summaryB := entry(indices[b])
+			if key == sortName {
+				return nameA < nameB
+			}
+			return value(summaryA) > value(summaryB)
+		})
+	}
+	if sortReverse {
+		for i, j := 0, len(indices)-1; i < j; i, j = i+1, j-1 {
+			indices[i], indices[j] = indices[j], indices[i]
+		}
+	}
+
+	// Keep the first entries
+	if limit && topFiles > 0 && len(indices) > topFiles {
+		indices = indices[:topFiles]
+	}
+	return indices
+}
+
+// arrangeFiles is a function that selects and orders file results, see arrange.
+func arrangeFiles(files []fileResult, defaultKey string, limit bool) []fileResult {
+	indices := arrange(len(files), func(i int) (string, languageSummary) {
+		var summary languageSummary
+		summary.addFile(files[i])
+		return files[i].Path, summary
+	}, defaultKey, limit)
+
+	arranged := make([]fileResult, len(indices))
+	for i, index := range indices {
+		arranged[i] = files[index]
+	}
+	return arranged
+}
+
+// arrangeLanguages is a function that selects and orders language aggregates, see arrange.
+// --top does not apply to languages.
+func arrangeLanguages(languages []languageSummary, defaultKey string) []languageSummary {
+	indices := arrange(len(languages), func(i int) (string, languageSummary) {
+		return languages[i].Language, languages[i]
+	}, defaultKey, false)
+
+	arranged := make([]languageSummary, len(indices))
+	for i, index := range indices {
+		arranged[i] = languages[index]
+	}
+	return arranged
+}
+
+// arrangeRows is a function that selects and orders the rows of a tabular report, see arrange.
+// --top applies to file, directory and owner rows.
+func arrangeRows(rows []reportRow, by string) []reportRow {
+	indices := arrange(len(rows), func(i int) (string, languageSummary) {
+		if by == byLanguage {
+			return rows[i].Language, rows[i].languageSummary
+		}
+		if isOwnerGranularity(by) {
+			return rows[i].Owner, rows[i].languageSummary
+		}
+		return rows[i].Path, rows[i].languageSummary
+	}, "", by != byLanguage)
+
+	arranged := make([]reportRow, len(indices))
+	for i, index := range indices {
+		arranged[i] = rows[index]
+	}
+	return arranged
+}
+
+// dirTree returns the directory tree of the report, with the subdirectories and the languages of every directory
+// selected and ordered following the --sort, --reverse, --top and --min-lines flags, see arrange.
+// Languages are sorted by lines of code and subdirectories by name unless --sort is given, and --top applies to subdirectories.
+func (r *report) dirTree() *dirNode {
+	root := buildDirTree(r.Files)
+	root.walk(0, func(dir *dirNode, depth int) {
+		dir.Languages = arrangeLanguages(dir.Languages, sortCode)
+
+		dirs := dir.Dirs
+		indices := arrange(len(dirs), func(i int) (string, languageSummary) {
+			return dirs[i].Path, dirs[i].Total
+		}, "", true)
+		dir.Dirs = make([]*dirNode, len(indices))
+		for i, index := range indices {
+			dir.Dirs[i] = dirs[index]
+		}
+	})
+	return root
+}
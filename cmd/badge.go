@@ -0,0 +1,235 @@
+// cmd/badge.go
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// badgeLabel, badgeColor, badgeLanguage and badgeOutput hold the values of the flags of the badge command.
+var (
+	badgeLabel    string
+	badgeColor    string
+	badgeLanguage string
+	badgeOutput   string
+)
+
+// badgeColors maps the color names of shields.io badges to their values.
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"lightgrey":   "#9f9f9f",
+	"grey":        "#555",
+	"blueviolet":  "#8a2be2",
+}
+
+// hexColorPattern matches CSS hexadecimal colors, with or without the leading '#'.
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// badgeCmd writes a badge of the number of lines of code, in the style of shields.io badges.
+var badgeCmd = &cobra.Command{
+	Use:   "badge",
+	Short: "Write an SVG badge of the number of lines of code",
+	Long: `Write an SVG badge of the number of lines of code, in the style of shields.io badges.
+
+The badge shows the code lines of every processed file, or of a single language with --language.
+Files are selected and counted exactly like the main command does, with the same configuration files and flags.
+Values of 1000 and more are abbreviated, e.g. 12.3k or 1.2M.`,
+	Example: `  locc badge --output loc.svg
+  locc badge --language go --label "go code" --color brightgreen --output go.svg`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, categories, err := loadRunConfig(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = writeBadge(config, categories)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// writeBadge is a function that counts the lines of code and writes the badge to the output file, or to the standard output.
+// It takes a configuration object and the set of enabled categories as input.
+// It returns an error if the language or the color is unknown, or if the lines cannot be counted or the badge cannot be written.
+func writeBadge(config *Config, categories categorySet) error {
+	color, err := resolveBadgeColor(badgeColor)
+	if err != nil {
+		return err
+	}
+	if badgeLanguage != "" && !isConfiguredLanguage(config, badgeLanguage) {
+		return fmt.Errorf("unknown language %q", badgeLanguage)
+	}
+
+	// Count the lines of every file selected by the configuration
+	r, err := collectReport(config, categories)
+	if err != nil {
+		return err
+	}
+
+	// Sum the code lines of the language across categories, or take the total
+	code := r.Total.Code
+	if badgeLanguage != "" {
+		code = 0
+		for _, lang := range r.Languages {
+			if lang.Language == badgeLanguage {
+				code += lang.Code
+			}
+		}
+	}
+
+	svg := renderBadge(badgeLabel, abbreviateCount(code), color)
+	if badgeOutput == "" {
+		_, err = os.Stdout.Write(svg)
+		return err
+	}
+	if err := os.WriteFile(badgeOutput, svg, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Printf("Output written to %s\n", badgeOutput)
+	return nil
+}
+
+// isConfiguredLanguage is a function that checks whether a language is defined in any category of the configuration.
+func isConfiguredLanguage(config *Config, name string) bool {
+	if _, ok := config.Languages[name]; ok {
+		return true
+	}
+	if _, ok := config.Stores[name]; ok {
+		return true
+	}
+	if _, ok := config.Documents[name]; ok {
+		return true
+	}
+	for _, category := range config.Categories {
+		if _, ok := category.Languages[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveBadgeColor is a function that returns the value of a badge color, given by shields.io name or as a hexadecimal color.
+func resolveBadgeColor(color string) (string, error) {
+	if value, ok := badgeColors[color]; ok {
+		return value, nil
+	}
+	if hexColorPattern.MatchString(color) {
+		return "#" + strings.TrimPrefix(color, "#"), nil
+	}
+	return "", fmt.Errorf("unknown color %q, expected a hexadecimal color or a shields.io color name", color)
+}
+
+// abbreviateCount is a function that formats a count for a badge, abbreviating thousands with 'k' and millions with 'M'.
+// Abbreviated values keep one decimal, dropped when it is 0: 999, 1k, 12.3k, 1.2M.
+func abbreviateCount(n int) string {
+	format := func(value float64, suffix string) string {
+		return strconv.FormatFloat(value, 'f', -1, 64) + suffix
+	}
+	round := func(value float64) float64 {
+		return float64(int64(value*10+0.5)) / 10
+	}
+	switch {
+	case n < 1000:
+		return strconv.Itoa(n)
+	case round(float64(n)/1e3) < 1000:
+		return format(round(float64(n)/1e3), "k")
+	default:
+		return format(round(float64(n)/1e6), "M")
+	}
+}
+
+// badgeTextWidth is a function that estimates the width of a text of the badge, in pixels.
+// The badge font is 11px Verdana, whose characters are approximated by a few width classes.
+func badgeTextWidth(text string) int {
+	width := 0.0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("iljtf.,:;!|' ", r):
+			width += 3.9
+		case strings.ContainsRune("mwMW", r):
+			width += 10.7
+		case unicode.IsUpper(r):
+			width += 7.6
+		case unicode.IsDigit(r):
+			width += 7
+		default:
+			width += 6.6
+		}
+	}
+	return int(width + 0.5)
+}
+
+// badgeTemplate is the SVG of a badge, in the flat style of shields.io.
+// Its arguments are the total width, the accessible title, the label width, the value width, the color,
+// the label center and text, and the value center and text.
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s">
+  <title>%[2]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r">
+    <rect width="%[1]d" height="20" rx="3" fill="#fff"/>
+  </clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[3]d" height="20" fill="#555"/>
+    <rect x="%[3]d" width="%[4]d" height="20" fill="%[5]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="11">
+    <text x="%[6]s" y="15" fill="#010101" fill-opacity=".3">%[7]s</text>
+    <text x="%[6]s" y="14">%[7]s</text>
+    <text x="%[8]s" y="15" fill="#010101" fill-opacity=".3">%[9]s</text>
+    <text x="%[8]s" y="14">%[9]s</text>
+  </g>
+</svg>
+`
+
+// renderBadge is a function that renders the SVG of a badge with a label, a value and the color of the value.
+func renderBadge(label, value, color string) []byte {
+	// escape escapes a text for XML content and attributes
+	escape := func(text string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(text))
+		return b.String()
+	}
+	center := func(offset, width int) string {
+		return strconv.FormatFloat(float64(offset)+float64(width)/2, 'f', 1, 64)
+	}
+
+	// Each part of the badge has 5px of padding on both sides of its text
+	labelWidth := badgeTextWidth(label) + 10
+	valueWidth := badgeTextWidth(value) + 10
+	title := escape(label + ": " + value)
+	return []byte(fmt.Sprintf(badgeTemplate,
+		labelWidth+valueWidth, title, labelWidth, valueWidth, color,
+		center(0, labelWidth), escape(label),
+		center(labelWidth, valueWidth), escape(value)))
+}
+
+// Registers the badge command and its flags.
+func init() {
+	badgeCmd.Flags().StringVar(&badgeLabel, "label", "lines of code", "Text of the left part of the badge")
+	badgeCmd.Flags().StringVar(&badgeColor, "color", "blue", "Color of the value, as a shields.io color name or a hexadecimal color")
+	badgeCmd.Flags().StringVar(&badgeLanguage, "language", "", "Count the code lines of this language only (optional)")
+	badgeCmd.Flags().StringVarP(&badgeOutput, "output", "o", "", "Output file name (optional)")
+	rootCmd.AddCommand(badgeCmd)
+}
@@ -0,0 +1,271 @@
+// cmd/blame.go
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// notCommitted is the author git blame gives to the lines not committed yet, also used for the files git does not track.
+const notCommitted = "Not Committed Yet"
+
+// ownerSummary holds the lines of one language attributed to an owner: an author, an email domain or a code owner.
+type ownerSummary struct {
+	// Owner is the author, as "Name <email>", the email domain, or the code owner.
+	Owner string
+
+	// languageSummary holds the language and the lines attributed to the owner. Files is the number of files with lines
+	// of the owner, and Bytes is left at 0 since files are shared between owners.
+	languageSummary
+}
+
+// isOwnerGranularity is a function that tells whether a granularity attributes the lines to owners.
+func isOwnerGranularity(by string) bool {
+	return by == byAuthor || by == byEmailDomain || by == byOwner
+}
+
+// blameLine is the author of a line, as given by git blame.
+type blameLine struct {
+	name string
+	mail string
+}
+
+// owner returns the owner of the line at a granularity: the author or the domain of their email.
+func (l blameLine) owner(by string) string {
+	// Uncommitted lines have no real author
+	if l.name == notCommitted && l.mail == "not.committed.yet" {
+		return notCommitted
+	}
+	if by == byEmailDomain {
+		_, domain, ok := strings.Cut(l.mail, "@")
+		if !ok || domain == "" {
+			return "(none)"
+		}
+		return strings.ToLower(domain)
+	}
+	// Emails are compared case-insensitively, like .mailmap does
+	return fmt.Sprintf("%s <%s>", l.name, strings.ToLower(l.mail))
+}
+
+// attributeLines is a function that attributes the lines of every file to their last author with git blame, setting the Owners of the files.
+// It takes the file system the files were read from, the directory it was opened on, the files and the kind of every line of every file.
+// Files read from a revision are blamed at that revision, with the .mailmap of the revision. Files of the working tree are blamed
+// with the .mailmap of the working tree, their uncommitted lines and the files git does not track being attributed to notCommitted.
+// Files are blamed concurrently, by as many git processes as there are CPUs.
+func attributeLines(fsys fs.FS, dir string, files []fileResult, kinds [][]lineKind) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("--by %s needs the git binary, which was not found: %w", reportBy, err)
+	}
+
+	// blame returns the authors of the lines of a file
+	var blame func(name string) ([]blameLine, error)
+	if tree, ok := fsys.(*treeFS); ok {
+		blame = func(name string) ([]blameLine, error) {
+			gitDir, commit, file, err := tree.fileSource(name)
+			if err != nil {
+				return nil, err
+			}
+			gitDir, err = filepath.Abs(gitDir)
+			if err != nil {
+				return nil, err
+			}
+			// Run git in the git directory, which has no .mailmap, so that only the one of the revision applies
+			return gitBlame(gitDir, "--git-dir="+gitDir, "-c", "mailmap.blob="+commit.String()+":.mailmap",
+				"blame", "--line-porcelain", commit.String(), "--", file)
+		}
+	} else {
+		tracked, err := listTrackedFiles(dir, submodulesRecurse)
+		if err != nil {
+			return fmt.Errorf("--by %s needs a git repository: %w", reportBy, err)
+		}
+		isTracked := make(map[string]bool, len(tracked))
+		for _, file := range tracked {
+			isTracked[file] = true
+		}
+		blame = func(name string) ([]blameLine, error) {
+			if !isTracked[name] {
+				return nil, nil
+			}
+			// Run git in the directory of the file, so that the files of submodules are blamed in their repository
+			return gitBlame(filepath.Join(dir, filepath.FromSlash(path.Dir(name))), "blame", "--line-porcelain", "--", path.Base(name))
+		}
+	}
+
+	// Blame the files with a pool of workers
+	jobs := make(chan int)
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				lines, err := blame(files[i].Path)
+				if err != nil {
+					errs[i] = fmt.Errorf("failed to blame %s: %w", files[i].Path, err)
+					continue
+				}
+				files[i].Owners = ownerLines(lines, kinds[i])
+			}
+		}()
+	}
+	for i := range files {
+		if len(kinds[i]) > 0 {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownerLines is a function that sums the lines of a file per owner at the --by granularity.
+// Lines without an author, such as the ones of files git does not track, are attributed to notCommitted.
+func ownerLines(lines []blameLine, kinds []lineKind) map[string]lineStats {
+	owners := make(map[string]lineStats)
+	for i, kind := range kinds {
+		owner := notCommitted
+		if i < len(lines) {
+			owner = lines[i].owner(reportBy)
+		}
+		stats := owners[owner]
+		stats.addLine(kind)
+		owners[owner] = stats
+	}
+	return owners
+}
+
+// gitBlame is a function that runs git blame in a directory with the given arguments, which must select the --line-porcelain output,
+// and returns the author of every line.
+func gitBlame(dir string, args ...string) ([]blameLine, error) {
+	output, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every line is a header, the commit information and the content of the line after a tab
+	var lines []blameLine
+	var current blameLine
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(output)+1)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			lines = append(lines, current)
+		case strings.HasPrefix(text, "author "):
+			current.name = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "author-mail "):
+			current.mail = strings.Trim(strings.TrimPrefix(text, "author-mail "), "<>")
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// summarizeOwners is a function that aggregates the lines attributed to owners per owner and language.
+// It returns the aggregates sorted by owner, language name and category.
+func summarizeOwners(files []fileResult) []ownerSummary {
+	byOwner := make(map[[3]string]*ownerSummary)
+	for _, file := range files {
+		for owner, stats := range file.Owners {
+			key := [3]string{owner, file.Language, file.Category}
+			summary, ok := byOwner[key]
+			if !ok {
+				summary = &ownerSummary{Owner: owner, languageSummary: languageSummary{Language: file.Language, Category: file.Category}}
+				byOwner[key] = summary
+			}
+			summary.Files++
+			summary.lineStats.add(stats)
+			if file.Generated {
+				summary.Generated++
+			}
+		}
+	}
+
+	owners := make([]ownerSummary, 0, len(byOwner))
+	for _, summary := range byOwner {
+		owners = append(owners, *summary)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Owner != owners[j].Owner {
+			return owners[i].Owner < owners[j].Owner
+		}
+		if owners[i].Language != owners[j].Language {
+			return owners[i].Language < owners[j].Language
+		}
+		return owners[i].Category < owners[j].Category
+	})
+	return owners
+}
+
+// ownerTotal is an owner with the totals of its lines and its split per language.
+type ownerTotal struct {
+	Owner     string
+	Total     languageSummary
+	Languages []languageSummary
+}
+
+// ownerTotals returns the owners of the report with their totals, the owners and their languages selected and ordered
+// following the --sort, --reverse, --top and --min-lines flags, see arrange. They are sorted by lines of code unless --sort is given.
+func (r *report) ownerTotals() []ownerTotal {
+	var owners []ownerTotal
+	for _, summary := range r.Owners {
+		if len(owners) == 0 || owners[len(owners)-1].Owner != summary.Owner {
+			owners = append(owners, ownerTotal{Owner: summary.Owner})
+		}
+		owner := &owners[len(owners)-1]
+		// A file has a single language, so the files of the languages add up
+		owner.Total.Files += summary.Files
+		owner.Total.lineStats.add(summary.lineStats)
+		owner.Total.Generated += summary.Generated
+		owner.Languages = append(owner.Languages, summary.languageSummary)
+	}
+
+	indices := arrange(len(owners), func(i int) (string, languageSummary) {
+		return owners[i].Owner, owners[i].Total
+	}, sortCode, true)
+	arranged := make([]ownerTotal, len(indices))
+	for i, index := range indices {
+		arranged[i] = owners[index]
+		arranged[i].Languages = arrangeLanguages(arranged[i].Languages, sortCode)
+	}
+	return arranged
+}
+
+// ownerHeader is a function that returns the header of the owner column at a granularity.
+func ownerHeader(by string) string {
+	switch by {
+	case byEmailDomain:
+		return "Domain"
+	case byOwner:
+		return "Owner"
+	default:
+		return "Author"
+	}
+}
+
+// unownedFiles returns the files of the report without code owner, sorted by path.
+func (r *report) unownedFiles() []fileResult {
+	var files []fileResult
+	for _, file := range r.Files {
+		if _, ok := file.Owners[unowned]; ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
@@ -0,0 +1,96 @@
+// cmd/categories.go
+package cmd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Names of the built-in categories.
+// Languages are always enabled, stores and documents are enabled with the --data and --docs flags.
+const (
+	categoryLanguages = "languages"
+	categoryStores    = "stores"
+	categoryDocuments = "documents"
+)
+
+// builtinCategories lists the built-in categories in the order they are checked when detecting a language.
+var builtinCategories = []string{categoryLanguages, categoryStores, categoryDocuments}
+
+// categorySet records which categories are enabled for a run.
+// The key of the map is the category name, and the value tells whether the category is enabled.
+type categorySet map[string]bool
+
+// isBuiltinCategory is a function that checks whether a category name is the name of a built-in category.
+func isBuiltinCategory(name string) bool {
+	for _, builtin := range builtinCategories {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// customCategoryNames is a function that returns the names of the user-defined categories of a configuration, sorted by name.
+// Custom categories are checked in this order when detecting a language, before the built-in ones.
+func customCategoryNames(config *Config) []string {
+	names := make([]string, 0, len(config.Categories))
+	for name := range config.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// categoryNames is a function that returns the names of all categories of a configuration:
+// the built-in categories followed by the custom categories sorted by name.
+func categoryNames(config *Config) []string {
+	return append(append([]string{}, builtinCategories...), customCategoryNames(config)...)
+}
+
+// resolveCategories is a function that decides which categories are enabled for a run.
+// The built-in languages category is always enabled, stores and documents follow the --data and --docs settings,
+// and custom categories follow their 'enabled' key.
+// The category overrides of the profile, if any, are applied next, and the --category and --no-category flags last.
+// It returns an error if a custom category uses the name of a built-in one or if an unknown category is referenced.
+func resolveCategories(config *Config, profile *ProfileConfig, enable, disable []string) (categorySet, error) {
+	categories := categorySet{
+		categoryLanguages: true,
+		categoryStores:    enableStores,
+		categoryDocuments: enableDocuments,
+	}
+
+	// Custom categories start with the state defined in the configuration
+	for name, category := range config.Categories {
+		if isBuiltinCategory(name) {
+			return nil, fmt.Errorf("category %q is reserved for the built-in category of the same name", name)
+		}
+		categories[name] = category.Enabled
+	}
+
+	// Apply the overrides of the profile
+	if profile != nil {
+		for name, enabled := range profile.Categories {
+			if _, ok := categories[name]; !ok {
+				return nil, fmt.Errorf("profile references unknown category %q", name)
+			}
+			categories[name] = enabled
+		}
+	}
+
+	// Apply the command-line flags
+	for _, name := range enable {
+		if _, ok := categories[name]; !ok {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		categories[name] = true
+	}
+	for _, name := range disable {
+		if _, ok := categories[name]; !ok {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		categories[name] = false
+	}
+
+	return categories, nil
+}
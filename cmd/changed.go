@@ -0,0 +1,141 @@
+// cmd/changed.go
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// countChangedLines is a function that counts the lines added and removed in the files of the current directory changed relative to a base:
+// the merge base of HEAD and the --base revision, or HEAD itself. Committed, staged and unstaged changes all count, and so do untracked
+// files unless only the files tracked by git are processed. Files are selected exactly like the main command does, and the report is
+// written like the diff command does to every output given with --output.
+func countChangedLines(config *Config, categories categorySet, targets []outputTarget) error {
+	if revision != "" {
+		return fmt.Errorf("--changed compares the working tree, it cannot be used with --rev")
+	}
+	for _, target := range targets {
+		if _, ok := diffFormats[target.format]; !ok {
+			return fmt.Errorf("--changed cannot write the %s format, expected text, json, csv or tsv", target.format)
+		}
+	}
+	if reportBy != byFile && reportBy != byLanguage {
+		return fmt.Errorf("--changed writes rows by %s or %s, not by %s", byFile, byLanguage, reportBy)
+	}
+	diffBy = reportBy
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("--changed needs the git binary, which was not found: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	// Find the base of the changes
+	base, side := "HEAD", diffSide{Name: "HEAD"}
+	if changedBase != "" {
+		output, err := runGit(cwd, "merge-base", changedBase, "HEAD")
+		if err != nil {
+			return fmt.Errorf("failed to find the merge base of %s and HEAD: %w", changedBase, err)
+		}
+		base, side.Name = strings.TrimSpace(string(output)), "merge base with "+changedBase
+	}
+	from, err := openRevisionFS(cwd, base, submoduleMode)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+	side.Revision = from.commit.Hash.String()
+
+	// List the changed files, and limit both sides to them
+	files, err := listChangedFiles(cwd, side.Revision, !gitMode)
+	if err != nil {
+		return err
+	}
+	to, err := openSourceFS(cwd)
+	if err != nil {
+		return err
+	}
+	if closer, ok := to.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Both sides are selected with the configuration of the working tree
+	r, err := diffSources(diffTree{fsys: newTrackedFS(from, files), config: config, categories: categories},
+		diffTree{fsys: newTrackedFS(to, files), config: config, categories: categories})
+	if err != nil {
+		return err
+	}
+	r.ConfigHash = configHash(config, categories)
+	side.ConfigHash = r.ConfigHash
+	r.From, r.To = side, diffSide{Name: "working tree", ConfigHash: r.ConfigHash}
+
+	// Status messages go to the standard error if a report is written to the standard output, so as not to corrupt it
+	status := os.Stdout
+	for _, target := range targets {
+		if target.path == "" {
+			status = os.Stderr
+		}
+	}
+	for _, target := range targets {
+		writer := diffFormats[target.format]
+		err := writeOutput(target.path, func(w io.Writer) error {
+			return writer(w, r)
+		})
+		if err != nil {
+			return err
+		}
+		if target.path != "" {
+			fmt.Fprintf(status, "Output written to %s\n", target.path)
+		}
+	}
+	return nil
+}
+
+// listChangedFiles is a function that lists the files of a directory changed relative to a commit with the git binary:
+// the files changed since the commit, staged or not, and the untracked files that are not ignored if requested.
+// Renamed files are listed under both names, and submodules are left out.
+// It returns the slash-separated paths of the files relative to the directory.
+func listChangedFiles(dir, commit string, untracked bool) ([]string, error) {
+	output, err := runGit(dir, "diff", "--name-only", "-z", "--relative", "--no-renames", "--ignore-submodules", commit, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files changed since %s: %w", commit, err)
+	}
+	files := strings.Split(string(output), "\x00")
+	if untracked {
+		output, err := runGit(dir, "ls-files", "-z", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the untracked files: %w", err)
+		}
+		files = append(files, strings.Split(string(output), "\x00")...)
+	}
+
+	// The outputs end with a separator
+	var changed []string
+	for _, file := range files {
+		if file != "" {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+// runGit is a function that runs the git binary in a directory and returns its output, or an error holding the message git printed.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+	return output, nil
+}
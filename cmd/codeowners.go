@@ -0,0 +1,206 @@
+// cmd/codeowners.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// unowned is the owner of the files no CODEOWNERS rule gives an owner to.
+const unowned = "(unowned)"
+
+// codeOwnersPaths lists the places of the CODEOWNERS file in a repository, in the order GitHub looks for them.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnersRule is a rule of a CODEOWNERS file: the files matching a pattern and their owners.
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// assignCodeOwners is a function that attributes the lines of every file to the owners CODEOWNERS gives it, setting the Owners of the files.
+// It takes the file system the files were read from and the directory it was opened on. The CODEOWNERS file is read from the root of
+// the repository holding the directory, at the revision with --rev, or from the directory itself outside of a repository.
+// A file with several owners counts for each of them, and a file with none for unowned.
+func assignCodeOwners(fsys fs.FS, dir string, files []fileResult) error {
+	root, content, err := readCodeOwners(fsys, dir)
+	if err != nil {
+		return err
+	}
+	rules := parseCodeOwners(content)
+
+	// Match the paths of the files relative to the root of the repository
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+	prefix, err := filepath.Rel(root, absDir)
+	if err != nil {
+		return fmt.Errorf("failed to get path of %s in the repository: %w", dir, err)
+	}
+	for i := range files {
+		owners := matchCodeOwners(rules, path.Join(filepath.ToSlash(prefix), files[i].Path))
+		if len(owners) == 0 {
+			owners = []string{unowned}
+		}
+		files[i].Owners = make(map[string]lineStats, len(owners))
+		for _, owner := range owners {
+			files[i].Owners[owner] = files[i].lineStats
+		}
+	}
+	return nil
+}
+
+// readCodeOwners is a function that reads the CODEOWNERS file of the repository holding a directory.
+// It returns the root of the repository and the content of the file, or an error if none of codeOwnersPaths exists.
+func readCodeOwners(fsys fs.FS, dir string) (string, []byte, error) {
+	// With --rev, read the file from the root tree of the revision
+	if tree, ok := fsys.(*treeFS); ok {
+		rootFS, err := openTreeFS(tree.repo, tree.repo.worktree, tree.commit.Hash.String(), submodulesSkip)
+		if err != nil {
+			return "", nil, err
+		}
+		defer rootFS.Close()
+		for _, name := range codeOwnersPaths {
+			content, err := rootFS.ReadFile(name)
+			if err == nil {
+				return tree.repo.worktree, content, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+		}
+		return "", nil, fmt.Errorf("no CODEOWNERS file in %s at revision %s", strings.Join(codeOwnersPaths, ", "), tree.commit.Hash)
+	}
+
+	// Outside of a repository, the directory stands for its root
+	root, _, err := findGitDir(dir)
+	if err != nil {
+		root, err = filepath.Abs(dir)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+		}
+	}
+	for _, name := range codeOwnersPaths {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err == nil {
+			return root, content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+	return "", nil, fmt.Errorf("no CODEOWNERS file in %s of %s", strings.Join(codeOwnersPaths, ", "), root)
+}
+
+// parseCodeOwners is a function that parses the rules of a CODEOWNERS file, each line being a pattern followed by its owners.
+// Blank lines and comments starting with '#' are skipped. A rule without owners leaves the matching files without owner.
+// A line whose pattern cannot be parsed is skipped with a warning on the standard error, like GitHub does, and the other rules still apply.
+func parseCodeOwners(content []byte) []codeOwnersRule {
+	var rules []codeOwnersRule
+	for i, line := range splitLines(content) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern, err := compileCodeOwnersPattern(fields[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping line %d of CODEOWNERS: %v\n", i+1, err)
+			continue
+		}
+		rule := codeOwnersRule{pattern: pattern}
+		for _, owner := range fields[1:] {
+			// The rest of the line is a comment
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.owners = append(rule.owners, owner)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// matchCodeOwners is a function that returns the owners of a file, given by its slash-separated path relative to the root of the repository.
+// The last rule matching the file wins, like on GitHub.
+func matchCodeOwners(rules []codeOwnersRule, name string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(name) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// compileCodeOwnersPattern is a function that converts a CODEOWNERS pattern into a regular expression matching file paths.
+// Patterns follow the rules of .gitignore files, like on GitHub: a pattern with a slash at the start or in the middle is relative to
+// the root of the repository, and otherwise matches at any depth. '*' and '?' match within a path segment and '**' across segments.
+// A pattern matching a directory matches every file below it, unless its last segment is '*', which only matches the files directly
+// in the directory, and a pattern ending with a slash only matches directories. Negations and character ranges are not supported.
+func compileCodeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, "[]") {
+		return nil, fmt.Errorf("%q: negations and character ranges are not supported", pattern)
+	}
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if segment == "**" {
+			// A leading or middle '**' matches any number of directories, a trailing one everything below
+			if last {
+				expr.WriteString(".*")
+			} else {
+				expr.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		for j := 0; j < len(segment); j++ {
+			switch c := segment[j]; c {
+			case '*':
+				expr.WriteString("[^/]*")
+			case '?':
+				expr.WriteString("[^/]")
+			case '\\':
+				// An escaped character is matched literally
+				if j+1 < len(segment) {
+					j++
+				}
+				expr.WriteString(regexp.QuoteMeta(segment[j : j+1]))
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		if !last {
+			expr.WriteString("/")
+		}
+	}
+
+	last := segments[len(segments)-1]
+	switch {
+	case last == "**":
+		expr.WriteString("$")
+	case dirOnly:
+		expr.WriteString("/.*$")
+	case last == "*" && len(segments) > 1:
+		expr.WriteString("$")
+	default:
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
@@ -3,11 +3,16 @@ package cmd
 
 import (
 	"embed"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v2"
 )
 
@@ -23,6 +28,12 @@ var defaultConfig embed.FS
 //go:embed default_local_config.yaml
 var defaultLocalConfig embed.FS
 
+// configSchema is an embedded file system that contains the JSON Schema of the configuration file.
+// This variable is used by the 'config schema' command.
+//
+//go:embed config_schema.json
+var configSchema embed.FS
+
 // Config struct represents the configuration for the program.
 // Languages field is a map that contains the configuration for each programming language.
 // The key of the map is the language name, and the value is a LanguageConfig struct.
@@ -35,13 +46,64 @@ var defaultLocalConfig embed.FS
 // Includes field is a map that contains the inclusion configuration for specific files.
 // The key of the map is the file name, and the value is an interface{} that can be either a slice of interfaces or a map of interfaces.
 // MaxFileSize field is an int64 that represents the maximum size of a file that can be processed.
+// Profiles field is a map that contains named sets of overrides selectable with the --profile flag.
+// The key of the map is the profile name, and the value is a ProfileConfig struct.
+// Categories field is a map that contains user-defined categories in addition to languages, stores and documents.
+// The key of the map is the category name, and the value is a CategoryConfig struct.
 type Config struct {
-	Languages   map[string]LanguageConfig `yaml:"languages"`
-	Stores      map[string]LanguageConfig `yaml:"stores"`
-	Documents   map[string]LanguageConfig `yaml:"documents"`
-	Excludes    map[string]interface{}    `yaml:"excludes"`
-	Includes    map[string]interface{}    `yaml:"includes"`
-	MaxFileSize int64                     `yaml:"max_file_size"`
+	Languages   map[string]LanguageConfig `yaml:"languages" json:"languages" toml:"languages"`
+	Stores      map[string]LanguageConfig `yaml:"stores" json:"stores" toml:"stores"`
+	Documents   map[string]LanguageConfig `yaml:"documents" json:"documents" toml:"documents"`
+	Categories  map[string]CategoryConfig `yaml:"categories,omitempty" json:"categories,omitempty" toml:"categories,omitempty"`
+	Excludes    map[string]interface{}    `yaml:"excludes" json:"excludes" toml:"excludes"`
+	Includes    map[string]interface{}    `yaml:"includes" json:"includes" toml:"includes"`
+	MaxFileSize int64                     `yaml:"max_file_size" json:"max_file_size" toml:"max_file_size"`
+	Profiles    map[string]ProfileConfig  `yaml:"profiles,omitempty" json:"profiles,omitempty" toml:"profiles,omitempty"`
+}
+
+// ProfileConfig struct represents a named profile, a set of overrides applied on top of the merged configuration.
+// Excludes and Includes have the same format as in Config and replace the filters of the same key.
+// The remaining fields override the corresponding command-line settings unless the flag is given explicitly.
+// Fields that are not set in the profile leave the configuration and the settings unchanged.
+type ProfileConfig struct {
+	// Excludes is a map of exclusions replacing the exclusions of the same key.
+	Excludes map[string]interface{} `yaml:"excludes" json:"excludes" toml:"excludes"`
+
+	// Includes is a map of inclusions replacing the inclusions of the same key.
+	Includes map[string]interface{} `yaml:"includes" json:"includes" toml:"includes"`
+
+	// MaxFileSize replaces the maximum file size when set.
+	MaxFileSize int64 `yaml:"max_file_size" json:"max_file_size" toml:"max_file_size"`
+
+	// Data enables or disables the processing of data stores, like the --data flag.
+	Data *bool `yaml:"data" json:"data" toml:"data"`
+
+	// Docs enables or disables the processing of documents, like the --docs flag.
+	Docs *bool `yaml:"docs" json:"docs" toml:"docs"`
+
+	// Output is the file the results are written to, like the --output flag.
+	Output string `yaml:"output" json:"output" toml:"output"`
+
+	// Format is the output format, like the --format flag.
+	Format string `yaml:"format" json:"format" toml:"format"`
+
+	// Verbose enables or disables verbose output, like the --verbose flag.
+	Verbose *bool `yaml:"verbose" json:"verbose" toml:"verbose"`
+
+	// Categories enables or disables categories by name, like the --category and --no-category flags.
+	Categories map[string]bool `yaml:"categories" json:"categories" toml:"categories"`
+}
+
+// CategoryConfig struct represents a user-defined category of files, such as infrastructure or build files.
+// Enabled field tells whether the category is processed when neither --category nor --no-category mention it.
+// Languages field has the same format as the 'languages' section of Config.
+type CategoryConfig struct {
+	// Enabled tells whether the category is processed by default.
+	Enabled bool `yaml:"enabled" json:"enabled" toml:"enabled"`
+
+	// Languages is a map of the languages belonging to the category.
+	// Extensions listed here take precedence over the same extensions in the built-in categories.
+	Languages map[string]LanguageConfig `yaml:"languages" json:"languages" toml:"languages"`
 }
 
 // LanguageConfig struct represents the configuration for a specific programming language.
@@ -50,11 +112,11 @@ type Config struct {
 type LanguageConfig struct {
 	// Extensions is a slice of strings that contains the file extensions associated with the language.
 	// For example, for Go language, this field might contain ["go"].
-	Extensions []string `yaml:"extensions"`
+	Extensions []string `yaml:"extensions" json:"extensions" toml:"extensions"`
 
 	// Comment is a slice of strings that contains the comment symbols used in the language.
 	// For example, for HTML language, this field would contain ["<!--", "-->"], for Go it would contain ["//"].
-	Comment []string `yaml:"comment"`
+	Comment []string `yaml:"comment" json:"comment" toml:"comment"`
 }
 
 // FileExclusion struct represents the exclusion configuration for a specific file.
@@ -62,7 +124,7 @@ type LanguageConfig struct {
 type FileExclusion struct {
 	// Wordlists is of type ExclusionWordlists, which contains the lists of words to exclude and include.
 	// This field is used to specify the words that should be excluded or included from a file during processing.
-	Wordlists ExclusionWordlists `yaml:"wordlists"`
+	Wordlists ExclusionWordlists `yaml:"wordlists" json:"wordlists" toml:"wordlists"`
 }
 
 // ExclusionWordlists struct represents the wordlists configuration for exclusions.
@@ -72,12 +134,12 @@ type FileExclusion struct {
 type ExclusionWordlists struct {
 	// Exclude is a slice of strings that contains the list of words to exclude.
 	// If a word in this list is found in a file, it will be excluded during processing.
-	Exclude []string `yaml:"exclude,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty" toml:"exclude,omitempty"`
 
 	// Include is a slice of strings that contains the list of words to include.
 	// If a word in this list is found in a file, it will be included during processing,
 	// even if it is also present in the Exclude list.
-	Include []string `yaml:"include,omitempty"`
+	Include []string `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
 }
 
 // getUserHomeDir is a function that retrieves the user's home directory.
@@ -95,20 +157,61 @@ func getUserHomeDir() string {
 }
 
 // getGlobalConfigPath is a function that retrieves the path to the global configuration file.
-// It uses the filepath.Join function to join the user's home directory with the ".locc.yaml" filename.
+// It looks for a ".locc.yaml", ".locc.json" or ".locc.toml" file in the user's home directory, in that order.
 // The user's home directory is retrieved using the getUserHomeDir function.
-// The function returns the path to the global configuration file.
+// If none of them exists, the function returns the path of the ".locc.yaml" file, where the default configuration is written.
 func getGlobalConfigPath() string {
 	// Call getUserHomeDir to get the user's home directory
+	homeDir := getUserHomeDir()
+	// Return the path of the first configuration file found in the home directory
+	if path, ok := findConfigFile(homeDir); ok {
+		return path
+	}
 	// Join the home directory with the ".locc.yaml" filename using filepath.Join
-	// Return the path to the global configuration file
-	return filepath.Join(getUserHomeDir(), ".locc.yaml")
+	return filepath.Join(homeDir, localConfigName)
+}
+
+// findConfigFile is a function that looks for a configuration file in a directory.
+// It checks the names listed in configFileNames in order and returns the path of the first existing file and true,
+// or an empty string and false if the directory has no configuration file.
+func findConfigFile(dir string) (string, bool) {
+	if name, ok := findConfigFileFS(os.DirFS(dir), "."); ok {
+		return filepath.Join(dir, filepath.FromSlash(name)), true
+	}
+	return "", false
+}
+
+// findConfigFileFS is a function that looks for a configuration file in a directory of a file system.
+// It takes the file system and the slash-separated path of the directory within it as input.
+// It returns the path of the first of .locc.yaml, .locc.json and .locc.toml found in the directory, and whether one was found.
+func findConfigFileFS(fsys fs.FS, dir string) (string, bool) {
+	for _, name := range configFileNames {
+		configPath := path.Join(dir, name)
+		if info, err := fs.Stat(fsys, configPath); err == nil && !info.IsDir() {
+			return configPath, true
+		}
+	}
+	return "", false
+}
+
+// unmarshalConfig is a function that parses configuration data into a Config struct.
+// The format is chosen from the extension of the path: ".json" files are parsed as JSON, ".toml" files as TOML,
+// and any other file as YAML.
+func unmarshalConfig(path string, data []byte, config *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return json.Unmarshal(data, config)
+	case ".toml":
+		return toml.Unmarshal(data, config)
+	default:
+		return yaml.Unmarshal(data, config)
+	}
 }
 
 // loadGlobalConfig is a function that loads the global configuration file.
 // It first retrieves the path to the global configuration file using the getGlobalConfigPath function.
 // It then checks if the global configuration file exists at that path.
-// If it exists, it reads the file, unmarshals the YAML data into a Config struct, and returns the Config.
+// If it exists, it reads the file, unmarshals the YAML, JSON or TOML data into a Config struct, and returns the Config.
 // If the global configuration file does not exist, it loads the default configuration file using the retrieveDefaultConfig function,
 // marshals the default configuration into YAML data, writes the YAML data to the global configuration file path,
 // and returns the default configuration.
@@ -126,30 +229,16 @@ func loadGlobalConfig() (*Config, error) {
 			return nil, fmt.Errorf("failed to read global config: %w", err)
 		}
 
-		// Unmarshal the YAML data into a Config struct
+		// Unmarshal the data into a Config struct
 		var config Config
-		err = yaml.Unmarshal(globalConfigData, &config)
+		err = unmarshalConfig(globalConfigPath, globalConfigData, &config)
 		if err != nil {
 			// If there is an error unmarshalling the data, return the error
 			return nil, fmt.Errorf("failed to parse global config: %w", err)
 		}
 
 		// Initialize maps if they are nil
-		if config.Languages == nil {
-			config.Languages = make(map[string]LanguageConfig)
-		}
-		if config.Stores == nil {
-			config.Stores = make(map[string]LanguageConfig)
-		}
-		if config.Documents == nil {
-			config.Documents = make(map[string]LanguageConfig)
-		}
-		if config.Excludes == nil {
-			config.Excludes = make(map[string]interface{})
-		}
-		if config.Includes == nil {
-			config.Includes = make(map[string]interface{})
-		}
+		initConfigMaps(&config)
 
 		return &config, nil
 	} else if os.IsNotExist(err) {
@@ -173,6 +262,8 @@ func loadGlobalConfig() (*Config, error) {
 			// If there is an error writing the file, return the error
 			return nil, fmt.Errorf("failed to write default config to global config path: %w", err)
 		}
+		// Initialize maps if they are nil
+		initConfigMaps(defaultConfig)
 		// Return the default configuration
 		return defaultConfig, nil
 	} else {
@@ -207,70 +298,196 @@ func retrieveDefaultConfig() (*Config, error) {
 	return &config, nil
 }
 
+// initConfigMaps is a function that initializes the maps of a configuration if they are nil,
+// so that configurations can be merged into without checking every map first.
+func initConfigMaps(config *Config) {
+	if config.Languages == nil {
+		config.Languages = make(map[string]LanguageConfig)
+	}
+	if config.Stores == nil {
+		config.Stores = make(map[string]LanguageConfig)
+	}
+	if config.Documents == nil {
+		config.Documents = make(map[string]LanguageConfig)
+	}
+	if config.Excludes == nil {
+		config.Excludes = make(map[string]interface{})
+	}
+	if config.Includes == nil {
+		config.Includes = make(map[string]interface{})
+	}
+	if config.Categories == nil {
+		config.Categories = make(map[string]CategoryConfig)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]ProfileConfig)
+	}
+}
+
+// localConfigName is the file name of the configuration files written by locc.
+const localConfigName = ".locc.yaml"
+
+// configFileNames lists the accepted names of configuration files, in order of preference.
+// They are used for the global configuration in the home directory, the project-level configuration in the working directory
+// and the per-directory configurations discovered while walking the tree.
+var configFileNames = []string{localConfigName, ".locc.json", ".locc.toml"}
+
 // loadLocalConfig is a function that loads the local configuration file.
 // It takes a filename as an argument, which is the path to the local configuration file.
-// If the filename is not provided, it defaults to "./.locc.yaml".
+// If the filename is not provided, it defaults to the first of "./.locc.yaml", "./.locc.json" and "./.locc.toml" that exists.
 // The function checks if the local configuration file exists at the provided path.
-// If it exists, it reads the file, unmarshals the YAML data into a Config struct, and returns the Config.
+// If it exists, it reads the file, unmarshals the YAML, JSON or TOML data into a Config struct, and returns the Config.
 // If the local configuration file does not exist, it returns nil and nil for the Config and error.
 // If there is an error in any of these steps, it returns the error.
+// With --rev and no filename, the configuration file is read from the current directory as it is at the revision,
+// like the configuration files of the subdirectories, so that the working tree does not change the counts of a revision.
 func loadLocalConfig(filename string) (*Config, error) {
+	if filename == "" && revision != "" {
+		return loadRevisionConfig(".", revision)
+	}
+
 	// If a filename is provided, use that as the local configuration file path.
-	// Otherwise, use "./.locc.yaml" as the local configuration file path.
+	// Otherwise, look for a configuration file in the current directory.
 	var localConfigPath string
 	if filename != "" {
 		localConfigPath = filename
+	} else if path, ok := findConfigFile("."); ok {
+		localConfigPath = path
 	} else {
-		localConfigPath = "./.locc.yaml"
+		// If the local configuration file does not exist, return nil and nil for the Config and error
+		return nil, nil
 	}
 
 	// Check if the local configuration file exists at the provided path
 	if _, err := os.Stat(localConfigPath); err == nil {
-		// If it exists, read the file
-		localConfigData, err := os.ReadFile(localConfigPath)
-		if err != nil {
-			// If there is an error reading the file, return the error
-			return nil, fmt.Errorf("failed to read local config: %w", err)
-		}
-
-		// Declare a Config variable to hold the local configuration
-		var localConfig Config
-		// Unmarshal the YAML data into the Config variable
-		err = yaml.Unmarshal(localConfigData, &localConfig)
-		if err != nil {
-			// If there is an error unmarshalling the data, return the error
-			return nil, fmt.Errorf("failed to parse local config: %w", err)
-		}
-		// If there is no error, return the Config variable
-		// Initialize maps if they are nil
-		if localConfig.Languages == nil {
-			localConfig.Languages = make(map[string]LanguageConfig)
-		}
-		if localConfig.Stores == nil {
-			localConfig.Stores = make(map[string]LanguageConfig)
-		}
-		if localConfig.Documents == nil {
-			localConfig.Documents = make(map[string]LanguageConfig)
-		}
-		if localConfig.Excludes == nil {
-			localConfig.Excludes = make(map[string]interface{})
-		}
-		if localConfig.Includes == nil {
-			localConfig.Includes = make(map[string]interface{})
-		}
-
-		return &localConfig, nil
+		// If it exists, read and parse it
+		return readLocalConfig(localConfigPath)
 	}
 	// If the local configuration file does not exist, return nil and nil for the Config and error
 	return nil, nil
 }
 
+// loadRevisionConfig is a function that loads the configuration file of a directory as it is at a git revision.
+// It returns nil and nil for the Config and error if the directory has no configuration file at the revision.
+func loadRevisionConfig(dir, rev string) (*Config, error) {
+	fsys, err := openRevisionFS(dir, rev, submoduleMode)
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+
+	return loadConfigFS(fsys)
+}
+
+// loadTreeConfig is a function that loads the local configuration of a tree counted at a revision or in another directory,
+// such as a commit sampled by the history command or a side of a comparison.
+// The file given with --config is read from disk and applies to every tree, otherwise the configuration file of the root
+// of the tree is used. A nil file system stands for a tree without files, which has no configuration file.
+func loadTreeConfig(fsys fs.FS) (*Config, error) {
+	if configFile != "" {
+		return loadLocalConfig(configFile)
+	}
+	if fsys == nil {
+		return nil, nil
+	}
+	return loadConfigFS(fsys)
+}
+
+// loadConfigFS is a function that loads the configuration file of the root of a file system.
+// It returns nil and nil for the Config and error if the root has no configuration file.
+func loadConfigFS(fsys fs.FS) (*Config, error) {
+	name, ok := findConfigFileFS(fsys, ".")
+	if !ok {
+		return nil, nil
+	}
+	return readConfigFS(fsys, name)
+}
+
+// readLocalConfig is a function that reads and parses a local configuration file.
+// It takes the path to the file as an argument.
+// It returns the parsed Config with all of its maps initialized, or an error if the file cannot be read or parsed.
+func readLocalConfig(path string) (*Config, error) {
+	// Read the file
+	localConfigData, err := os.ReadFile(path)
+	if err != nil {
+		// If there is an error reading the file, return the error
+		return nil, fmt.Errorf("failed to read local config %s: %w", path, err)
+	}
+	return parseLocalConfig(path, localConfigData)
+}
+
+// readConfigFS is a function that reads and parses a configuration file from a file system, such as the tree of a git revision.
+// It takes the file system and the slash-separated path of the file within it as input.
+func readConfigFS(fsys fs.FS, name string) (*Config, error) {
+	// Read the file
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		// If there is an error reading the file, return the error
+		return nil, fmt.Errorf("failed to read local config %s: %w", name, err)
+	}
+	return parseLocalConfig(name, data)
+}
+
+// parseLocalConfig is a function that parses the content of a local configuration file.
+// The format is chosen by the extension of the path, which is also used in error messages.
+func parseLocalConfig(path string, localConfigData []byte) (*Config, error) {
+	// Declare a Config variable to hold the local configuration
+	var localConfig Config
+	// Unmarshal the data into the Config variable
+	err := unmarshalConfig(path, localConfigData, &localConfig)
+	if err != nil {
+		// If there is an error unmarshalling the data, return the error
+		return nil, fmt.Errorf("failed to parse local config %s: %w", path, err)
+	}
+
+	// Initialize maps if they are nil
+	initConfigMaps(&localConfig)
+
+	return &localConfig, nil
+}
+
+// cloneConfig is a function that returns a copy of a configuration.
+// The maps of the copy are new maps holding the same entries, so the copy can be merged into
+// without modifying the original. The entries themselves are shared, they are never modified in place.
+func cloneConfig(config *Config) *Config {
+	clone := *config
+	clone.Languages = make(map[string]LanguageConfig, len(config.Languages))
+	for lang, langConfig := range config.Languages {
+		clone.Languages[lang] = langConfig
+	}
+	clone.Stores = make(map[string]LanguageConfig, len(config.Stores))
+	for store, storeConfig := range config.Stores {
+		clone.Stores[store] = storeConfig
+	}
+	clone.Documents = make(map[string]LanguageConfig, len(config.Documents))
+	for doc, docConfig := range config.Documents {
+		clone.Documents[doc] = docConfig
+	}
+	clone.Categories = make(map[string]CategoryConfig, len(config.Categories))
+	for name, category := range config.Categories {
+		clone.Categories[name] = category
+	}
+	clone.Excludes = make(map[string]interface{}, len(config.Excludes))
+	for lang, exclusions := range config.Excludes {
+		clone.Excludes[lang] = exclusions
+	}
+	clone.Includes = make(map[string]interface{}, len(config.Includes))
+	for lang, inclusions := range config.Includes {
+		clone.Includes[lang] = inclusions
+	}
+	clone.Profiles = make(map[string]ProfileConfig, len(config.Profiles))
+	for name, profile := range config.Profiles {
+		clone.Profiles[name] = profile
+	}
+	return &clone
+}
+
 // mergeConfigs function merges the global and local configurations.
 // It takes two pointers to Config structs as arguments: globalConfig and localConfig.
 // If globalConfig is nil, it returns localConfig.
 // If localConfig is nil, it returns globalConfig.
 // If both configurations are not nil, it merges the local configuration into the global configuration.
-// It checks if the local configuration has any language configurations, exclusions, or max file size.
+// It checks if the local configuration has any language, store, document or category configurations, filters, profiles or max file size.
 // If it does, it updates the corresponding fields in the global configuration.
 // Finally, it returns the merged global configuration.
 func mergeConfigs(globalConfig, localConfig *Config) *Config {
@@ -289,6 +506,16 @@ func mergeConfigs(globalConfig, localConfig *Config) *Config {
 			globalConfig.Languages[lang] = langConfig
 		}
 	}
+	// If localConfig has any store, document or category configurations, merge them into globalConfig
+	for store, storeConfig := range localConfig.Stores {
+		globalConfig.Stores[store] = storeConfig
+	}
+	for doc, docConfig := range localConfig.Documents {
+		globalConfig.Documents[doc] = docConfig
+	}
+	for name, category := range localConfig.Categories {
+		globalConfig.Categories[name] = category
+	}
 	// If localConfig has any exclusions, merge them into globalConfig
 	if localConfig.Excludes != nil {
 		for lang, exclusions := range localConfig.Excludes {
@@ -307,10 +534,99 @@ func mergeConfigs(globalConfig, localConfig *Config) *Config {
 		globalConfig.MaxFileSize = localConfig.MaxFileSize
 	}
 
+	// If localConfig has any profiles, merge them into globalConfig
+	if localConfig.Profiles != nil {
+		for name, profile := range localConfig.Profiles {
+			globalConfig.Profiles[name] = profile
+		}
+	}
+
 	// Return the merged global configuration
 	return globalConfig
 }
 
+// applyProfile function applies the profile with the given name to a merged configuration.
+// It must be called before the filters are processed, since profile filters are in their raw form.
+// The exclusions and inclusions of the profile replace those of the same key, and its max file size replaces the configured one.
+// It returns the applied profile so that the caller can apply its settings, or nil if no profile name is given.
+// If the configuration has no profile with the given name, it returns an error.
+func applyProfile(config *Config, name string) (*ProfileConfig, error) {
+	// No profile selected, nothing to apply
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	// Replace the filters of the same key with the ones of the profile
+	for lang, exclusions := range profile.Excludes {
+		config.Excludes[lang] = exclusions
+	}
+	for lang, inclusions := range profile.Includes {
+		config.Includes[lang] = inclusions
+	}
+	// If the profile has a max file size, update the max file size in config
+	if profile.MaxFileSize > 0 {
+		config.MaxFileSize = profile.MaxFileSize
+	}
+
+	return &profile, nil
+}
+
+// layerConfigs function layers the configuration of a subdirectory on top of the configuration of its parent directory.
+// Unlike mergeConfigs, it leaves the parent configuration untouched and returns a new one.
+// Language, store, document and category configurations of the child replace those of the parent with the same name,
+// while filters are added to the parent filters of the same key, the way nested .gitignore files add patterns.
+// Both configurations must have had their filters processed with processFilters.
+func layerConfigs(parent, child *Config) *Config {
+	config := cloneConfig(parent)
+
+	for lang, langConfig := range child.Languages {
+		config.Languages[lang] = langConfig
+	}
+	for store, storeConfig := range child.Stores {
+		config.Stores[store] = storeConfig
+	}
+	for doc, docConfig := range child.Documents {
+		config.Documents[doc] = docConfig
+	}
+	for name, category := range child.Categories {
+		config.Categories[name] = category
+	}
+	config.Excludes = layerFilter(config.Excludes, child.Excludes)
+	config.Includes = layerFilter(config.Includes, child.Includes)
+	if child.MaxFileSize > 0 {
+		config.MaxFileSize = child.MaxFileSize
+	}
+
+	return config
+}
+
+// layerFilter function adds the rules of a processed child filter to a processed parent filter.
+// Rules of the child replace rules of the parent for the same file name.
+func layerFilter(parent, child map[string]interface{}) map[string]interface{} {
+	for lang, rules := range child {
+		childRules, ok := rules.(map[string][]string)
+		if !ok {
+			continue
+		}
+		merged := make(map[string][]string)
+		if parentRules, ok := parent[lang].(map[string][]string); ok {
+			for filename, wordlist := range parentRules {
+				merged[filename] = wordlist
+			}
+		}
+		for filename, wordlist := range childRules {
+			merged[filename] = wordlist
+		}
+		parent[lang] = merged
+	}
+	return parent
+}
+
 func processFilters(config *Config) {
 	config.Excludes = processFilter(config.Excludes)
 	config.Includes = processFilter(config.Includes)
@@ -323,6 +639,13 @@ func processFilter(filter map[string]interface{}) map[string]interface{} {
 			filter[lang] = processSimpleFilter(v)
 		case map[interface{}]interface{}:
 			filter[lang] = processDetailedFilter(v)
+		case map[string]interface{}:
+			// JSON and TOML objects have string keys
+			rules := make(map[interface{}]interface{}, len(v))
+			for filename, details := range v {
+				rules[filename] = details
+			}
+			filter[lang] = processDetailedFilter(rules)
 		}
 	}
 	return filter
@@ -0,0 +1,46 @@
+// cmd/config_cmd.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the subcommands dealing with the configuration file.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration of locc",
+}
+
+// schemaCmd prints the JSON Schema of the configuration file.
+// Editors can use it to autocomplete and validate .locc.yaml, .locc.json and .locc.toml files.
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long: `Print the JSON Schema of the configuration file.
+
+The schema describes .locc.yaml, .locc.json and .locc.toml files, so editors can autocomplete and validate them.
+For example, with the YAML language server, save the schema and reference it at the top of .locc.yaml:
+
+  locc config schema > ~/.locc.schema.json
+  # yaml-language-server: $schema=~/.locc.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Read the schema from the embedded file system
+		schema, err := configSchema.ReadFile("config_schema.json")
+		if err != nil {
+			return fmt.Errorf("failed to read config schema: %w", err)
+		}
+		// Write the schema to the standard output
+		_, err = os.Stdout.Write(schema)
+		return err
+	},
+}
+
+// Registers the config command and its subcommands.
+func init() {
+	configCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
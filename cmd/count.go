@@ -0,0 +1,159 @@
+// cmd/count.go
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// lineStats holds the number of blank, comment and code lines of a file or of a group of files.
+type lineStats struct {
+	// Blank is the number of lines containing only whitespace.
+	Blank int
+
+	// Comment is the number of lines containing only a comment.
+	Comment int
+
+	// Code is the number of lines containing code, possibly followed by a comment.
+	Code int
+}
+
+// add adds the counts of other to the counts of s.
+func (s *lineStats) add(other lineStats) {
+	s.Blank += other.Blank
+	s.Comment += other.Comment
+	s.Code += other.Code
+}
+
+// sub subtracts the counts of other from the counts of s.
+func (s *lineStats) sub(other lineStats) {
+	s.Blank -= other.Blank
+	s.Comment -= other.Comment
+	s.Code -= other.Code
+}
+
+// NonEmpty returns the number of lines that are not blank, which is what locc historically reported as lines of code.
+func (s lineStats) NonEmpty() int {
+	return s.Comment + s.Code
+}
+
+// Lines returns the total number of lines.
+func (s lineStats) Lines() int {
+	return s.Blank + s.Comment + s.Code
+}
+
+// lineKind is the classification of a single line.
+type lineKind int
+
+const (
+	lineBlank lineKind = iota
+	lineComment
+	lineCode
+)
+
+// lineClassifier classifies the lines of a file one at a time, based on the comment syntax of its language.
+// A comment syntax with one string is a single-line comment marker, and one with two strings holds the
+// opening and closing markers of multi-line comments. Lines starting with a comment marker are comment lines,
+// blank lines are blank even inside a multi-line comment, and every other line is a code line.
+type lineClassifier struct {
+	lineComment string
+	blockStart  string
+	blockEnd    string
+	inBlock     bool
+}
+
+// newLineClassifier is a function that creates a line classifier for the given comment syntax.
+func newLineClassifier(comment []string) *lineClassifier {
+	classifier := &lineClassifier{}
+	switch len(comment) {
+	case 1:
+		classifier.lineComment = comment[0]
+	case 2:
+		classifier.blockStart, classifier.blockEnd = comment[0], comment[1]
+	}
+	return classifier
+}
+
+// classify returns the kind of the next line of the file.
+func (c *lineClassifier) classify(line string) lineKind {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "":
+		return lineBlank
+	case c.inBlock:
+		// The line is inside a multi-line comment, check whether the comment ends on it
+		if end := strings.Index(trimmed, c.blockEnd); end >= 0 {
+			c.inBlock = false
+			// Code after the end of the comment makes it a code line
+			if strings.TrimSpace(trimmed[end+len(c.blockEnd):]) != "" {
+				return lineCode
+			}
+		}
+		return lineComment
+	case c.lineComment != "" && strings.HasPrefix(trimmed, c.lineComment):
+		return lineComment
+	case c.blockStart != "" && strings.HasPrefix(trimmed, c.blockStart):
+		rest := trimmed[len(c.blockStart):]
+		end := strings.Index(rest, c.blockEnd)
+		if end < 0 {
+			// The comment continues on the next lines
+			c.inBlock = true
+			return lineComment
+		}
+		// Code after the end of the comment makes it a code line
+		if strings.TrimSpace(rest[end+len(c.blockEnd):]) != "" {
+			return lineCode
+		}
+		return lineComment
+	default:
+		return lineCode
+	}
+}
+
+// splitLines is a function that splits content into lines, without their line terminators.
+// Unlike bufio.Scanner with its default buffer, it handles lines of any length.
+func splitLines(content []byte) []string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// countLines is a function that counts the blank, comment and code lines of a file.
+// It takes the content of the file and the comment syntax of its language as input.
+func countLines(content []byte, comment []string) lineStats {
+	var stats lineStats
+	classifier := newLineClassifier(comment)
+	for _, line := range splitLines(content) {
+		stats.addLine(classifier.classify(line))
+	}
+	return stats
+}
+
+// classifyLines is a function that splits the content of a file into lines and classifies each of them.
+// It takes the content of the file and the comment syntax of its language as input.
+func classifyLines(content []byte, comment []string) ([]string, []lineKind) {
+	lines := splitLines(content)
+	kinds := make([]lineKind, len(lines))
+	classifier := newLineClassifier(comment)
+	for i, line := range lines {
+		kinds[i] = classifier.classify(line)
+	}
+	return lines, kinds
+}
+
+// addLine counts one line of the given kind.
+func (s *lineStats) addLine(kind lineKind) {
+	switch kind {
+	case lineBlank:
+		s.Blank++
+	case lineComment:
+		s.Comment++
+	case lineCode:
+		s.Code++
+	}
+}
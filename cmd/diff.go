@@ -0,0 +1,484 @@
+// cmd/diff.go
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// diffFormat, diffOutput and diffBy hold the values of the flags of the diff command.
+var (
+	diffFormat string
+	diffOutput string
+	diffBy     string
+)
+
+// Statuses of the files of a diff.
+const (
+	fileAdded    = "added"
+	fileRemoved  = "removed"
+	fileModified = "modified"
+)
+
+// diffStats holds the lines added, removed and modified between two versions, by kind of line.
+// Within a changed block of lines, removed and added lines of the same kind are paired as modified lines,
+// and the lines left over are removed or added.
+type diffStats struct {
+	Added    lineStats
+	Removed  lineStats
+	Modified lineStats
+}
+
+// add adds the counts of other to the counts of s.
+func (s *diffStats) add(other diffStats) {
+	s.Added.add(other.Added)
+	s.Removed.add(other.Removed)
+	s.Modified.add(other.Modified)
+}
+
+// net returns the number of lines gained by kind, the added lines minus the removed lines, which may be negative.
+func (s diffStats) net() lineStats {
+	net := s.Added
+	net.sub(s.Removed)
+	return net
+}
+
+// fileDiff holds the changes of a single file.
+type fileDiff struct {
+	// Path is the path of the file relative to the compared directories, with forward slashes.
+	Path string
+
+	// Language and Category are the language detected for the file and the category it belongs to.
+	Language string
+	Category string
+
+	// Status tells whether the file was added, removed or modified.
+	Status string
+
+	diffStats
+}
+
+// diffFileCounts holds the number of files added, removed and modified.
+type diffFileCounts struct {
+	Added    int
+	Removed  int
+	Modified int
+}
+
+// changed returns the number of files added, removed or modified.
+func (c diffFileCounts) changed() int {
+	return c.Added + c.Removed + c.Modified
+}
+
+// languageDiff holds the aggregated changes of the files of one language, or of all files for the totals.
+type languageDiff struct {
+	// Language and Category identify the language. They are empty for the totals.
+	Language string
+	Category string
+
+	// Files holds the number of files added, removed and modified.
+	Files diffFileCounts
+
+	// FilesWith holds the number of files with added, removed and modified lines. A modified file may count for several kinds of change.
+	FilesWith diffFileCounts
+
+	diffStats
+}
+
+// addFile adds the changes of a file to the aggregate.
+func (l *languageDiff) addFile(file fileDiff) {
+	switch file.Status {
+	case fileAdded:
+		l.Files.Added++
+	case fileRemoved:
+		l.Files.Removed++
+	default:
+		l.Files.Modified++
+	}
+	if file.Added != (lineStats{}) {
+		l.FilesWith.Added++
+	}
+	if file.Removed != (lineStats{}) {
+		l.FilesWith.Removed++
+	}
+	if file.Modified != (lineStats{}) {
+		l.FilesWith.Modified++
+	}
+	l.diffStats.add(file.diffStats)
+}
+
+// diffSide is one of the two compared versions: a directory, or a git revision and the commit it resolved to.
+type diffSide struct {
+	Name     string
+	Revision string
+
+	// ConfigHash identifies the effective configuration the files of the side were selected with.
+	ConfigHash string
+}
+
+// diffTree holds the files of one side of a comparison, and the configuration and categories that select them.
+type diffTree struct {
+	fsys       fs.FS
+	config     *Config
+	categories categorySet
+}
+
+// diffReport holds the results of a comparison, shared by every diff output format.
+type diffReport struct {
+	From diffSide
+	To   diffSide
+
+	// Files holds the changes of every changed file, sorted by path.
+	Files []fileDiff
+
+	// Languages holds the per-language aggregates, sorted by the number of code lines gained or lost, the largest change first.
+	Languages []languageDiff
+
+	// Total holds the aggregate of all files.
+	Total languageDiff
+
+	// ConfigHash identifies the effective configuration of the new side.
+	ConfigHash string
+}
+
+// diffWriter writes a diff report in one output format.
+type diffWriter func(w io.Writer, r *diffReport) error
+
+// diffFormats maps the names accepted by the --format flag of the diff command to their writers.
+var diffFormats = map[string]diffWriter{
+	"text": writeTextDiff,
+	"json": writeJSONDiff,
+	"csv":  writeCSVDiff,
+	"tsv":  writeTSVDiff,
+}
+
+// diffCmd compares the lines of code of two git revisions or two directories.
+var diffCmd = &cobra.Command{
+	Use:   "diff A B",
+	Short: "Compare the lines of code of two git revisions or two directories",
+	Long: `Compare the lines of code of two git revisions or two directories.
+
+A and B are directories, or revisions of the git repository of the current directory (commits, tags or
+branches, read from the object database without checking them out). Files are selected and classified
+exactly like the main command does, with the same configuration files and flags.
+
+The lines of every file present on both sides are compared with a line diff. Within a block of changed
+lines, removed and added lines of the same kind (code, comment or blank) count as modified, and the lines
+left over as removed or added. Files present on one side only are entirely added or removed, and so are
+moved files, whose path changed.`,
+	Example: `  locc diff v2.0.0 HEAD
+  locc diff --format json --output changes.json v2.0.0 main
+  locc diff ../release-1.0 .`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		run, err := loadGlobalRunConfig(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = runDiff(run, args[0], args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runDiff is a function that compares two revisions or directories and writes the diff report.
+// It takes the configuration of the run, layered with the local configuration of each side, and the names of the two sides as input.
+func runDiff(run *runConfig, from, to string) error {
+	writer, ok := diffFormats[diffFormat]
+	if !ok {
+		var names []string
+		for name := range diffFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown output format %q, expected one of %s", diffFormat, strings.Join(names, ", "))
+	}
+	if diffBy != byFile && diffBy != byLanguage {
+		return fmt.Errorf("unknown granularity %q, expected %s or %s", diffBy, byFile, byLanguage)
+	}
+	if revision != "" {
+		return fmt.Errorf("--rev cannot be used with diff, give the revisions as arguments")
+	}
+
+	fromFS, fromSide, err := openDiffSource(from)
+	if err != nil {
+		return err
+	}
+	if closer, ok := fromFS.(io.Closer); ok {
+		defer closer.Close()
+	}
+	toFS, toSide, err := openDiffSource(to)
+	if err != nil {
+		return err
+	}
+	if closer, ok := toFS.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Every side is selected with its own configuration files, so that a revision is compared with the configuration it had
+	fromTree, err := loadDiffTree(run, fromFS, &fromSide)
+	if err != nil {
+		return err
+	}
+	toTree, err := loadDiffTree(run, toFS, &toSide)
+	if err != nil {
+		return err
+	}
+
+	r, err := diffSources(fromTree, toTree)
+	if err != nil {
+		return err
+	}
+	r.From, r.To = fromSide, toSide
+	r.ConfigHash = toSide.ConfigHash
+
+	return writeOutput(diffOutput, func(w io.Writer) error {
+		return writer(w, r)
+	})
+}
+
+// openDiffSource is a function that opens one side of a comparison: a directory if one exists with that name, otherwise a git revision
+// of the repository of the current directory, see openRevisionFS. Directories honor --git.
+func openDiffSource(name string) (fs.FS, diffSide, error) {
+	side := diffSide{Name: name}
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		fsys, err := openSourceFS(name)
+		return fsys, side, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, side, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	tree, err := openRevisionFS(cwd, name, submoduleMode)
+	if err != nil {
+		return nil, side, fmt.Errorf("%s is neither a directory nor a revision: %w", name, err)
+	}
+	side.Revision = tree.commit.Hash.String()
+	return tree, side, nil
+}
+
+// loadDiffTree is a function that layers the local configuration of one side of a comparison on the configuration of the run,
+// and records the hash of the resulting configuration in the side.
+func loadDiffTree(run *runConfig, fsys fs.FS, side *diffSide) (diffTree, error) {
+	localConfig, err := loadTreeConfig(fsys)
+	if err != nil {
+		return diffTree{}, fmt.Errorf("failed to load the configuration of %s: %w", side.Name, err)
+	}
+	config, categories, err := run.layer(localConfig)
+	if err != nil {
+		return diffTree{}, fmt.Errorf("failed to load the configuration of %s: %w", side.Name, err)
+	}
+	side.ConfigHash = configHash(config, categories)
+	return diffTree{fsys: fsys, config: config, categories: categories}, nil
+}
+
+// diffSources is a function that compares the files selected by their configuration in the file systems of two sides.
+// Files whose content is identical are skipped without being compared, and files whose language changed
+// count as removed from the old language and added to the new one.
+func diffSources(fromTree, toTree diffTree) (*diffReport, error) {
+	from, to := fromTree.fsys, toTree.fsys
+	fromFiles, err := sourceFilesByPath(fromTree.config, from, fromTree.categories)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := sourceFilesByPath(toTree.config, to, toTree.categories)
+	if err != nil {
+		return nil, err
+	}
+
+	// Visit every path of either side, in order
+	var paths []string
+	for name := range fromFiles {
+		paths = append(paths, name)
+	}
+	for name := range toFiles {
+		if _, ok := fromFiles[name]; !ok {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+
+	var files []fileDiff
+	for _, name := range paths {
+		oldFile, inOld := fromFiles[name]
+		newFile, inNew := toFiles[name]
+
+		var oldContent, newContent []byte
+		if inOld {
+			if oldContent, err = fs.ReadFile(from, name); err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+			}
+		}
+		if inNew {
+			if newContent, err = fs.ReadFile(to, name); err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+			}
+		}
+
+		// Compare the lines of the files present on both sides in the same language
+		if inOld && inNew && oldFile.Language == newFile.Language && oldFile.Category == newFile.Category {
+			if bytes.Equal(oldContent, newContent) {
+				continue
+			}
+			stats := diffContents(oldContent, oldFile.Comment, newContent, newFile.Comment)
+			if stats == (diffStats{}) {
+				continue
+			}
+			files = append(files, fileDiff{Path: name, Language: newFile.Language, Category: newFile.Category, Status: fileModified, diffStats: stats})
+			continue
+		}
+
+		// Otherwise the old file is entirely removed and the new one entirely added
+		if inOld {
+			removed := fileDiff{Path: name, Language: oldFile.Language, Category: oldFile.Category, Status: fileRemoved}
+			removed.Removed = countLines(oldContent, oldFile.Comment)
+			files = append(files, removed)
+		}
+		if inNew {
+			added := fileDiff{Path: name, Language: newFile.Language, Category: newFile.Category, Status: fileAdded}
+			added.Added = countLines(newContent, newFile.Comment)
+			files = append(files, added)
+		}
+	}
+	return newDiffReport(files), nil
+}
+
+// sourceFilesByPath is a function that lists the files selected by the configuration in a file system, by path.
+// Files of unsupported languages are left out.
+func sourceFilesByPath(config *Config, fsys fs.FS, categories categorySet) (map[string]sourceFile, error) {
+	list, err := buildFileList(config, fsys, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build file list: %w", err)
+	}
+	files := make(map[string]sourceFile, len(list))
+	for _, file := range list {
+		if file.Language != "" {
+			files[file.Path] = file
+		}
+	}
+	return files, nil
+}
+
+// diffContents is a function that compares two versions of a file, given with the comment syntax of their language.
+// Every line is classified within its own version of the file, and the changed blocks of lines found by diffLines
+// are split into modified, removed and added lines of each kind.
+func diffContents(oldContent []byte, oldComment []string, newContent []byte, newComment []string) diffStats {
+	oldLines, oldKinds := classifyLines(oldContent, oldComment)
+	newLines, newKinds := classifyLines(newContent, newComment)
+
+	var stats diffStats
+	for _, hunk := range diffLines(oldLines, newLines) {
+		var removed, added lineStats
+		for _, kind := range oldKinds[hunk.aStart:hunk.aEnd] {
+			removed.addLine(kind)
+		}
+		for _, kind := range newKinds[hunk.bStart:hunk.bEnd] {
+			added.addLine(kind)
+		}
+
+		// Pair the removed and added lines of the same kind
+		modified := lineStats{
+			Blank:   min(removed.Blank, added.Blank),
+			Comment: min(removed.Comment, added.Comment),
+			Code:    min(removed.Code, added.Code),
+		}
+		removed.sub(modified)
+		added.sub(modified)
+		stats.add(diffStats{Added: added, Removed: removed, Modified: modified})
+	}
+	return stats
+}
+
+// newDiffReport is a function that builds a diff report from the changes of the files.
+func newDiffReport(files []fileDiff) *diffReport {
+	r := &diffReport{Files: files}
+
+	// Aggregate the files per language
+	index := make(map[string]int)
+	for _, file := range files {
+		key := file.Category + "/" + file.Language
+		i, ok := index[key]
+		if !ok {
+			i = len(r.Languages)
+			index[key] = i
+			r.Languages = append(r.Languages, languageDiff{Language: file.Language, Category: file.Category})
+		}
+		r.Languages[i].addFile(file)
+		r.Total.addFile(file)
+	}
+
+	// Sort the languages by the number of code lines gained or lost, then by name
+	sort.SliceStable(r.Languages, func(i, j int) bool {
+		a, b := abs(r.Languages[i].net().Code), abs(r.Languages[j].net().Code)
+		if a != b {
+			return a > b
+		}
+		return r.Languages[i].Language < r.Languages[j].Language
+	})
+	return r
+}
+
+// summary returns the net change of code lines of every language, such as "+12,340 Go, −3,100 JavaScript".
+// Languages whose number of code lines did not change are left out.
+func (r *diffReport) summary() string {
+	var parts []string
+	for _, lang := range r.Languages {
+		if net := lang.net().Code; net != 0 {
+			parts = append(parts, formatSigned(net, "−")+" "+displayLanguage(lang.Language))
+		}
+	}
+	if len(parts) == 0 {
+		return "no change in lines of code"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSigned is a function that formats a number with its sign and thousands separators, such as "+12,340", or "0".
+// It takes the minus sign to use, since reports for people read better with a true minus sign than with a hyphen.
+func formatSigned(n int, minus string) string {
+	sign := "+"
+	switch {
+	case n == 0:
+		return "0"
+	case n < 0:
+		sign = minus
+	}
+	digits := fmt.Sprint(abs(n))
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// abs is a function that returns the absolute value of an integer.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Registers the diff command and its flags.
+func init() {
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "Output format: text, json, csv or tsv")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Output file name (optional)")
+	diffCmd.Flags().StringVar(&diffBy, "by", byFile, "Granularity of the csv and tsv rows: file or language")
+	diffCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "List the changed files in the text format")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,237 @@
+// cmd/format_cloc.go
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// clocURL is reported in the cloc_url field of the header of the cloc-compatible formats.
+const clocURL = "github.com/zewebdev1337/locc"
+
+// clocHeader is the header block of the cloc-compatible formats.
+type clocHeader struct {
+	URL            string  `json:"cloc_url" xml:"cloc_url"`
+	Version        string  `json:"cloc_version" xml:"cloc_version"`
+	ElapsedSeconds float64 `json:"elapsed_seconds" xml:"elapsed_seconds"`
+	Files          int     `json:"n_files" xml:"n_files"`
+	Lines          int     `json:"n_lines" xml:"n_lines"`
+	FilesPerSecond float64 `json:"files_per_second" xml:"files_per_second"`
+	LinesPerSecond float64 `json:"lines_per_second" xml:"lines_per_second"`
+}
+
+// clocLanguage is a per-language entry of the cloc-compatible formats.
+type clocLanguage struct {
+	Name  string
+	Files int
+	lineStats
+}
+
+// newClocHeader is a function that builds the header block of a report.
+func newClocHeader(r *report) clocHeader {
+	header := clocHeader{
+		URL:            clocURL,
+		Version:        version,
+		ElapsedSeconds: roundFloat(r.Elapsed.Seconds()),
+		Files:          r.Total.Files,
+		Lines:          r.Total.Lines(),
+	}
+	if seconds := r.Elapsed.Seconds(); seconds > 0 {
+		header.FilesPerSecond = roundFloat(float64(header.Files) / seconds)
+		header.LinesPerSecond = roundFloat(float64(header.Lines) / seconds)
+	}
+	return header
+}
+
+// roundFloat is a function that rounds a value to 4 decimals, the precision cloc reports.
+func roundFloat(value float64) float64 {
+	return math.Round(value*1e4) / 1e4
+}
+
+// clocLanguages is a function that returns the per-language entries of a report, keyed by the names cloc uses.
+// Languages sharing a display name across categories are merged. Entries are sorted by code lines, largest first, like cloc does.
+func clocLanguages(r *report) []clocLanguage {
+	byName := make(map[string]*clocLanguage)
+	var languages []*clocLanguage
+	for _, lang := range r.Languages {
+		name := displayLanguage(lang.Language)
+		entry, ok := byName[name]
+		if !ok {
+			entry = &clocLanguage{Name: name}
+			byName[name] = entry
+			languages = append(languages, entry)
+		}
+		entry.Files += lang.Files
+		entry.add(lang.lineStats)
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		if languages[i].Code != languages[j].Code {
+			return languages[i].Code > languages[j].Code
+		}
+		return languages[i].Name < languages[j].Name
+	})
+
+	entries := make([]clocLanguage, len(languages))
+	for i, entry := range languages {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// writeClocJSONReport is a function that writes a report in the format of cloc --json.
+// The object holds the header, one entry per language and the SUM entry, in that order.
+func writeClocJSONReport(w io.Writer, r *report) error {
+	// encoding/json sorts map keys, so the object is written member by member to keep cloc's order
+	var buf bytes.Buffer
+	writeMember := func(name string, value interface{}, last bool) error {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(value, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		buf.WriteString("  ")
+		buf.Write(key)
+		buf.WriteString(": ")
+		buf.Write(data)
+		if !last {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+
+	// clocCounts is a language entry, with cloc's member names
+	type clocCounts struct {
+		Files   int `json:"nFiles"`
+		Blank   int `json:"blank"`
+		Comment int `json:"comment"`
+		Code    int `json:"code"`
+	}
+
+	buf.WriteString("{\n")
+	if err := writeMember("header", newClocHeader(r), false); err != nil {
+		return err
+	}
+	for _, lang := range clocLanguages(r) {
+		if err := writeMember(lang.Name, clocCounts{lang.Files, lang.Blank, lang.Comment, lang.Code}, false); err != nil {
+			return err
+		}
+	}
+	sum := clocCounts{r.Total.Files, r.Total.Blank, r.Total.Comment, r.Total.Code}
+	if err := writeMember("SUM", sum, true); err != nil {
+		return err
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeClocYAMLReport is a function that writes a report in the format of cloc --yaml.
+func writeClocYAMLReport(w io.Writer, r *report) error {
+	header := newClocHeader(r)
+	document := yaml.MapSlice{
+		{Key: "header", Value: yaml.MapSlice{
+			{Key: "cloc_url", Value: header.URL},
+			{Key: "cloc_version", Value: header.Version},
+			{Key: "elapsed_seconds", Value: header.ElapsedSeconds},
+			{Key: "n_files", Value: header.Files},
+			{Key: "n_lines", Value: header.Lines},
+			{Key: "files_per_second", Value: header.FilesPerSecond},
+			{Key: "lines_per_second", Value: header.LinesPerSecond},
+		}},
+	}
+	for _, lang := range clocLanguages(r) {
+		document = append(document, yaml.MapItem{Key: lang.Name, Value: yaml.MapSlice{
+			{Key: "nFiles", Value: lang.Files},
+			{Key: "blank", Value: lang.Blank},
+			{Key: "comment", Value: lang.Comment},
+			{Key: "code", Value: lang.Code},
+		}})
+	}
+	document = append(document, yaml.MapItem{Key: "SUM", Value: yaml.MapSlice{
+		{Key: "blank", Value: r.Total.Blank},
+		{Key: "comment", Value: r.Total.Comment},
+		{Key: "code", Value: r.Total.Code},
+		{Key: "nFiles", Value: r.Total.Files},
+	}})
+
+	data, err := yaml.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	// cloc starts the document with a marker and a comment naming the tool
+	if _, err := fmt.Fprintf(w, "---\n# %s\n", clocURL); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// clocXMLResults is the root element of the cloc --xml format.
+type clocXMLResults struct {
+	XMLName   xml.Name         `xml:"results"`
+	Header    clocHeader       `xml:"header"`
+	Languages clocXMLLanguages `xml:"languages"`
+}
+
+// clocXMLLanguages holds the language elements and the total of the cloc --xml format.
+type clocXMLLanguages struct {
+	Languages []clocXMLLanguage `xml:"language"`
+	Total     clocXMLTotal      `xml:"total"`
+}
+
+// clocXMLLanguage is a language element of the cloc --xml format.
+type clocXMLLanguage struct {
+	Name    string `xml:"name,attr"`
+	Files   int    `xml:"files_count,attr"`
+	Blank   int    `xml:"blank,attr"`
+	Comment int    `xml:"comment,attr"`
+	Code    int    `xml:"code,attr"`
+}
+
+// clocXMLTotal is the total element of the cloc --xml format.
+type clocXMLTotal struct {
+	Files   int `xml:"sum_files,attr"`
+	Blank   int `xml:"blank,attr"`
+	Comment int `xml:"comment,attr"`
+	Code    int `xml:"code,attr"`
+}
+
+// writeClocXMLReport is a function that writes a report in the format of cloc --xml.
+func writeClocXMLReport(w io.Writer, r *report) error {
+	results := clocXMLResults{Header: newClocHeader(r)}
+	for _, lang := range clocLanguages(r) {
+		results.Languages.Languages = append(results.Languages.Languages, clocXMLLanguage{
+			Name:    lang.Name,
+			Files:   lang.Files,
+			Blank:   lang.Blank,
+			Comment: lang.Comment,
+			Code:    lang.Code,
+		})
+	}
+	results.Languages.Total = clocXMLTotal{r.Total.Files, r.Total.Blank, r.Total.Comment, r.Total.Code}
+
+	data, err := xml.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
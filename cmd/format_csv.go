@@ -0,0 +1,129 @@
+// cmd/format_csv.go
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumn describes a column of the CSV and TSV output formats.
+type csvColumn struct {
+	// name is the name of the column, used in the header and by the --columns flag.
+	name string
+
+	// value returns the value of the column for a row.
+	value func(row reportRow) string
+}
+
+// csvColumns lists the columns available in the CSV and TSV output formats.
+// 'generated' is the number of generated files of the row, so it is 0 or 1 for file rows.
+var csvColumns = []csvColumn{
+	{"path", func(row reportRow) string { return row.Path }},
+	{"parent", func(row reportRow) string { return row.Parent }},
+	{"depth", func(row reportRow) string { return strconv.Itoa(row.Depth) }},
+	{"owner", func(row reportRow) string { return row.Owner }},
+	{"language", func(row reportRow) string { return row.Language }},
+	{"category", func(row reportRow) string { return row.Category }},
+	{"files", func(row reportRow) string { return strconv.Itoa(row.Files) }},
+	{"code", func(row reportRow) string { return strconv.Itoa(row.Code) }},
+	{"comment", func(row reportRow) string { return strconv.Itoa(row.Comment) }},
+	{"blank", func(row reportRow) string { return strconv.Itoa(row.Blank) }},
+	{"bytes", func(row reportRow) string { return strconv.FormatInt(row.Bytes, 10) }},
+	{"generated", func(row reportRow) string { return strconv.Itoa(row.Generated) }},
+}
+
+// defaultCSVColumns lists the columns written for each granularity when --columns is not given.
+var defaultCSVColumns = map[string][]string{
+	byFile:        {"path", "language", "category", "code", "comment", "blank", "bytes", "generated"},
+	byLanguage:    {"language", "category", "files", "code", "comment", "blank", "bytes", "generated"},
+	byDirectory:   {"path", "parent", "depth", "language", "category", "files", "code", "comment", "blank", "bytes", "generated"},
+	byAuthor:      {"owner", "language", "category", "files", "code", "comment", "blank"},
+	byEmailDomain: {"owner", "language", "category", "files", "code", "comment", "blank"},
+	byOwner:       {"owner", "path", "language", "category", "files", "code", "comment", "blank"},
+}
+
+// selectCSVColumns is a function that resolves column names into columns.
+// If no names are given, the default columns of the granularity are used.
+func selectCSVColumns(names []string, by string) ([]csvColumn, error) {
+	if len(names) == 0 {
+		names = defaultCSVColumns[by]
+	}
+
+	var columns []csvColumn
+	for _, name := range names {
+		found := false
+		for _, column := range csvColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			var available []string
+			for _, column := range csvColumns {
+				available = append(available, column.name)
+			}
+			return nil, fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(available, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// writeCSVReport is a function that writes a report as RFC 4180 CSV, with a header row.
+func writeCSVReport(w io.Writer, r *report) error {
+	return writeDelimitedReport(w, r, ',')
+}
+
+// writeTSVReport is a function that writes a report as tab-separated values, with a header row.
+func writeTSVReport(w io.Writer, r *report) error {
+	return writeDelimitedReport(w, r, '\t')
+}
+
+// writeDelimitedReport is a function that writes the rows of a report at the granularity selected with --by and --depth,
+// with the columns selected with --columns, separated by the given delimiter.
+// Fields containing the delimiter, quotes or line breaks are quoted as described in RFC 4180.
+func writeDelimitedReport(w io.Writer, r *report, delimiter rune) error {
+	rows, err := r.rows(reportBy, treeDepth)
+	if err != nil {
+		return err
+	}
+	// Directory rows are already arranged, following the directory tree
+	if reportBy != byDirectory {
+		rows = arrangeRows(rows, reportBy)
+	}
+	columns, err := selectCSVColumns(reportColumns, reportBy)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	// RFC 4180 lines end with CRLF
+	writer.UseCRLF = delimiter == ','
+
+	// Write the header row
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.name
+	}
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+
+	// Write one record per row
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = column.value(row)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,270 @@
+// cmd/format_diff.go
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeTextDiff is a function that writes a diff report as an aligned table, like cloc --diff does.
+// Every language has a row per kind of change (added, removed and modified lines) with the number of files having lines of that kind,
+// sorted by the number of code lines gained or lost, followed by the net change and the number of changed files of all files,
+// and a one-line summary of the net change of code lines per language.
+// Verbose output adds a table of the changed files first.
+func writeTextDiff(w io.Writer, r *diffReport) error {
+	width, color := terminalWidth(w), styler(useColor(w))
+
+	header := fmt.Sprintf("Changes from %s to %s\n", describeDiffSide(r.From), describeDiffSide(r.To))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	// If verbose output is enabled, write a table of the changed files
+	if verbose && len(r.Files) > 0 {
+		files := &textTable{columns: []textColumn{{header: "File"}, {header: "Language"}, {header: "Change"}}}
+		files.columns = append(files.columns, statsColumns()...)
+		for _, file := range r.Files {
+			name, language := file.Path, file.Language
+			for _, change := range diffChanges(file.diffStats) {
+				files.addRow(append([]string{name, language, change.name}, statsCells(change.stats)...)...)
+				name, language = "", ""
+			}
+		}
+		if err := files.render(w, width, color); err != nil {
+			return err
+		}
+	}
+
+	table := &textTable{columns: []textColumn{{header: "Language"}, {header: "Change"}, {header: "Files", right: true}}}
+	table.columns = append(table.columns, statsColumns()...)
+	for _, lang := range r.Languages {
+		counts := map[string]int{fileAdded: lang.FilesWith.Added, fileRemoved: lang.FilesWith.Removed, fileModified: lang.FilesWith.Modified}
+		name := lang.Language
+		for _, change := range diffChanges(lang.diffStats) {
+			table.addRow(append([]string{name, change.name, strconv.Itoa(counts[change.name])}, statsCells(change.stats)...)...)
+			name = ""
+		}
+	}
+	net := r.Total.net()
+	table.setFooter("Total", "net", strconv.Itoa(r.Total.Files.changed()),
+		formatSigned(net.Blank, "-"), formatSigned(net.Comment, "-"), formatSigned(net.Code, "-"))
+	if err := table.render(w, width, color); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "Code: %s\n", r.summary())
+	return err
+}
+
+// describeDiffSide is a function that describes one side of a comparison, with the abbreviated commit of a revision.
+func describeDiffSide(side diffSide) string {
+	if side.Revision == "" {
+		return side.Name
+	}
+	return fmt.Sprintf("%s (%s)", side.Name, side.Revision[:7])
+}
+
+// diffChange is the lines of one kind of change: added, removed or modified.
+type diffChange struct {
+	name  string
+	stats lineStats
+}
+
+// diffChanges is a function that lists the kinds of change with lines, in the order added, removed, modified.
+func diffChanges(stats diffStats) []diffChange {
+	var changes []diffChange
+	for _, change := range []diffChange{{fileAdded, stats.Added}, {fileRemoved, stats.Removed}, {fileModified, stats.Modified}} {
+		if change.stats != (lineStats{}) {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// jsonDiffReport is the document written by the json format of the diff command.
+type jsonDiffReport struct {
+	SchemaVersion int                `json:"schema_version"`
+	LoccVersion   string             `json:"locc_version"`
+	ConfigHash    string             `json:"config_hash"`
+	From          jsonDiffSide       `json:"from"`
+	To            jsonDiffSide       `json:"to"`
+	Summary       string             `json:"summary"`
+	Files         []jsonFileDiff     `json:"files"`
+	Languages     []jsonLanguageDiff `json:"languages"`
+	Totals        jsonDiffTotals     `json:"totals"`
+}
+
+// jsonDiffSide is one of the two compared versions in the JSON diff report.
+type jsonDiffSide struct {
+	Name       string `json:"name"`
+	Revision   string `json:"revision,omitempty"`
+	ConfigHash string `json:"config_hash"`
+}
+
+// jsonLines holds the number of lines of each kind in the JSON diff report.
+type jsonLines struct {
+	Blank   int `json:"blank"`
+	Comment int `json:"comment"`
+	Code    int `json:"code"`
+}
+
+// jsonDiffStats holds the lines added, removed and modified in the JSON diff report.
+type jsonDiffStats struct {
+	Added    jsonLines `json:"added"`
+	Removed  jsonLines `json:"removed"`
+	Modified jsonLines `json:"modified"`
+}
+
+// jsonFileDiff is the record of a changed file in the JSON diff report.
+type jsonFileDiff struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	jsonDiffStats
+}
+
+// jsonDiffFiles holds the number of files added, removed and modified in the JSON diff report.
+type jsonDiffFiles struct {
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+}
+
+// jsonLanguageDiff is the aggregate of one language in the JSON diff report. Net is the number of lines gained, which may be negative.
+type jsonLanguageDiff struct {
+	Language string        `json:"language"`
+	Category string        `json:"category"`
+	Files    jsonDiffFiles `json:"files"`
+	jsonDiffStats
+	Net jsonLines `json:"net"`
+}
+
+// jsonDiffTotals is the aggregate of all files in the JSON diff report.
+type jsonDiffTotals struct {
+	Files jsonDiffFiles `json:"files"`
+	jsonDiffStats
+	Net jsonLines `json:"net"`
+}
+
+// newJSONLines is a function that converts line counts into their JSON record.
+func newJSONLines(stats lineStats) jsonLines {
+	return jsonLines{Blank: stats.Blank, Comment: stats.Comment, Code: stats.Code}
+}
+
+// newJSONDiffStats is a function that converts the changes of a file or a language into their JSON record.
+func newJSONDiffStats(stats diffStats) jsonDiffStats {
+	return jsonDiffStats{Added: newJSONLines(stats.Added), Removed: newJSONLines(stats.Removed), Modified: newJSONLines(stats.Modified)}
+}
+
+// newJSONDiffFiles is a function that converts file counts into their JSON record.
+func newJSONDiffFiles(counts diffFileCounts) jsonDiffFiles {
+	return jsonDiffFiles{Added: counts.Added, Removed: counts.Removed, Modified: counts.Modified}
+}
+
+// writeJSONDiff is a function that writes a diff report as a versioned JSON document, with the same schema version as the JSON report.
+func writeJSONDiff(w io.Writer, r *diffReport) error {
+	doc := &jsonDiffReport{
+		SchemaVersion: jsonSchemaVersion,
+		LoccVersion:   version,
+		ConfigHash:    r.ConfigHash,
+		From:          jsonDiffSide{Name: r.From.Name, Revision: r.From.Revision, ConfigHash: r.From.ConfigHash},
+		To:            jsonDiffSide{Name: r.To.Name, Revision: r.To.Revision, ConfigHash: r.To.ConfigHash},
+		Summary:       r.summary(),
+		Files:         make([]jsonFileDiff, 0, len(r.Files)),
+		Languages:     make([]jsonLanguageDiff, 0, len(r.Languages)),
+		Totals: jsonDiffTotals{
+			Files:         newJSONDiffFiles(r.Total.Files),
+			jsonDiffStats: newJSONDiffStats(r.Total.diffStats),
+			Net:           newJSONLines(r.Total.net()),
+		},
+	}
+	for _, file := range r.Files {
+		doc.Files = append(doc.Files, jsonFileDiff{
+			Path:          file.Path,
+			Language:      file.Language,
+			Category:      file.Category,
+			Status:        file.Status,
+			jsonDiffStats: newJSONDiffStats(file.diffStats),
+		})
+	}
+	for _, lang := range r.Languages {
+		doc.Languages = append(doc.Languages, jsonLanguageDiff{
+			Language:      lang.Language,
+			Category:      lang.Category,
+			Files:         newJSONDiffFiles(lang.Files),
+			jsonDiffStats: newJSONDiffStats(lang.diffStats),
+			Net:           newJSONLines(lang.net()),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writeCSVDiff is a function that writes a diff report as comma-separated values, see writeDelimitedDiff.
+func writeCSVDiff(w io.Writer, r *diffReport) error {
+	return writeDelimitedDiff(w, r, ',')
+}
+
+// writeTSVDiff is a function that writes a diff report as tab-separated values, see writeDelimitedDiff.
+func writeTSVDiff(w io.Writer, r *diffReport) error {
+	return writeDelimitedDiff(w, r, '\t')
+}
+
+// writeDelimitedDiff is a function that writes a diff report as delimiter-separated values, with a header row.
+// It writes one record per changed file, or per language with --by language, holding the lines added, removed and modified of each kind.
+func writeDelimitedDiff(w io.Writer, r *diffReport, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	// RFC 4180 lines end with CRLF
+	writer.UseCRLF = delimiter == ','
+
+	// statsFields returns the line counts of each kind of change
+	statsHeader := []string{
+		"code_added", "code_removed", "code_modified",
+		"comment_added", "comment_removed", "comment_modified",
+		"blank_added", "blank_removed", "blank_modified",
+	}
+	statsFields := func(stats diffStats) []string {
+		var fields []string
+		for _, kind := range []func(lineStats) int{
+			func(s lineStats) int { return s.Code },
+			func(s lineStats) int { return s.Comment },
+			func(s lineStats) int { return s.Blank },
+		} {
+			fields = append(fields, strconv.Itoa(kind(stats.Added)), strconv.Itoa(kind(stats.Removed)), strconv.Itoa(kind(stats.Modified)))
+		}
+		return fields
+	}
+
+	if diffBy == byLanguage {
+		if err := writer.Write(append([]string{"language", "category", "files_added", "files_removed", "files_modified"}, statsHeader...)); err != nil {
+			return err
+		}
+		for _, lang := range r.Languages {
+			record := []string{lang.Language, lang.Category,
+				strconv.Itoa(lang.Files.Added), strconv.Itoa(lang.Files.Removed), strconv.Itoa(lang.Files.Modified)}
+			if err := writer.Write(append(record, statsFields(lang.diffStats)...)); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := writer.Write(append([]string{"path", "language", "category", "status"}, statsHeader...)); err != nil {
+			return err
+		}
+		for _, file := range r.Files {
+			record := []string{file.Path, file.Language, file.Category, file.Status}
+			if err := writer.Write(append(record, statsFields(file.diffStats)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,115 @@
+// cmd/format_history.go
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// jsonHistory is the document written by the json format of the history command.
+// ConfigHash identifies the configuration of the newest sampled commit, and every sample holds the one it was counted with.
+type jsonHistory struct {
+	SchemaVersion int                 `json:"schema_version"`
+	LoccVersion   string              `json:"locc_version"`
+	ConfigHash    string              `json:"config_hash"`
+	Samples       []jsonHistorySample `json:"samples"`
+}
+
+// jsonHistorySample is the record of a sampled commit in the JSON history.
+type jsonHistorySample struct {
+	Commit     string         `json:"commit"`
+	Date       string         `json:"date"`
+	Subject    string         `json:"subject"`
+	ConfigHash string         `json:"config_hash"`
+	Totals     jsonTotals     `json:"totals"`
+	Languages  []jsonLanguage `json:"languages"`
+}
+
+// writeJSONHistory is a function that writes the samples of the history as a versioned JSON document, oldest commit first,
+// with the same schema version as the JSON report.
+func writeJSONHistory(w io.Writer, samples []historySample) error {
+	doc := &jsonHistory{
+		SchemaVersion: jsonSchemaVersion,
+		LoccVersion:   version,
+		Samples:       make([]jsonHistorySample, 0, len(samples)),
+	}
+	for _, sample := range samples {
+		r := sample.Report
+		doc.ConfigHash = r.ConfigHash
+		record := jsonHistorySample{
+			Commit:     sample.Commit.Hash.String(),
+			Date:       sample.Commit.Time.Format(time.RFC3339),
+			Subject:    sample.Commit.Subject,
+			ConfigHash: r.ConfigHash,
+			Totals: jsonTotals{
+				Files:   r.Total.Files,
+				Blank:   r.Total.Blank,
+				Comment: r.Total.Comment,
+				Code:    r.Total.Code,
+				Bytes:   r.Total.Bytes,
+			},
+			Languages: make([]jsonLanguage, 0, len(r.Languages)),
+		}
+		for _, lang := range r.Languages {
+			record.Languages = append(record.Languages, newJSONLanguage(lang))
+		}
+		doc.Samples = append(doc.Samples, record)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writeCSVHistory is a function that writes the samples of the history as comma-separated values, see writeDelimitedHistory.
+func writeCSVHistory(w io.Writer, samples []historySample) error {
+	return writeDelimitedHistory(w, samples, ',')
+}
+
+// writeTSVHistory is a function that writes the samples of the history as tab-separated values, see writeDelimitedHistory.
+func writeTSVHistory(w io.Writer, samples []historySample) error {
+	return writeDelimitedHistory(w, samples, '\t')
+}
+
+// writeDelimitedHistory is a function that writes the samples of the history as delimiter-separated values, with a header row.
+// It writes one record per sampled commit and language, oldest commit first, ready to be pivoted into a chart.
+// A commit without counted files gets a single record without language and with zero counts, so that every sampled commit appears.
+func writeDelimitedHistory(w io.Writer, samples []historySample, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	// RFC 4180 lines end with CRLF
+	writer.UseCRLF = delimiter == ','
+
+	if err := writer.Write([]string{"commit", "date", "language", "category", "files", "code", "comment", "blank", "bytes"}); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		commit, date := sample.Commit.Hash.String(), sample.Commit.Time.Format(time.RFC3339)
+		languages := sample.Report.Languages
+		if len(languages) == 0 {
+			languages = []languageSummary{{}}
+		}
+		for _, lang := range languages {
+			record := []string{
+				commit,
+				date,
+				lang.Language,
+				lang.Category,
+				strconv.Itoa(lang.Files),
+				strconv.Itoa(lang.Code),
+				strconv.Itoa(lang.Comment),
+				strconv.Itoa(lang.Blank),
+				strconv.FormatInt(lang.Bytes, 10),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
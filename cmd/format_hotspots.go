@@ -0,0 +1,148 @@
+// cmd/format_hotspots.go
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// writeTextHotspots is a function that writes a hotspot report as an aligned table, the highest score first.
+func writeTextHotspots(w io.Writer, r *hotspotReport) error {
+	width, color := terminalWidth(w), styler(useColor(w))
+
+	if _, err := fmt.Fprintf(w, "Hotspots %s, ranked by %s\n", describeWindow(r), r.Score); err != nil {
+		return err
+	}
+
+	table := &textTable{columns: []textColumn{
+		{header: "#", right: true},
+		{header: "File"},
+		{header: "Language"},
+		{header: "Code", right: true},
+		{header: "Commits", right: true},
+		{header: "Added", right: true},
+		{header: "Removed", right: true},
+		{header: "Score", right: true},
+	}}
+	for i, file := range r.Files {
+		table.addRow(strconv.Itoa(i+1), file.Path, file.Language, strconv.Itoa(file.Code), strconv.Itoa(file.Commits),
+			strconv.Itoa(file.Added), strconv.Itoa(file.Removed), strconv.FormatInt(file.Score, 10))
+	}
+	return table.render(w, width, color)
+}
+
+// describeWindow is a function that describes the window of the history of a hotspot report, with the abbreviated commit it ends at.
+func describeWindow(r *hotspotReport) string {
+	if r.Since.IsZero() {
+		return fmt.Sprintf("up to %s", r.Revision[:7])
+	}
+	return fmt.Sprintf("from %s to %s", r.Since.Format("2006-01-02"), r.Revision[:7])
+}
+
+// jsonHotspotReport is the document written by the json format of the hotspots command.
+type jsonHotspotReport struct {
+	SchemaVersion int           `json:"schema_version"`
+	LoccVersion   string        `json:"locc_version"`
+	ConfigHash    string        `json:"config_hash"`
+	Since         string        `json:"since,omitempty"`
+	Revision      string        `json:"revision"`
+	Score         string        `json:"score"`
+	Files         []jsonHotspot `json:"files"`
+}
+
+// jsonHotspot is the record of a file in the JSON hotspot report.
+type jsonHotspot struct {
+	Rank     int    `json:"rank"`
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Category string `json:"category"`
+	Blank    int    `json:"blank"`
+	Comment  int    `json:"comment"`
+	Code     int    `json:"code"`
+	Commits  int    `json:"commits"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Score    int64  `json:"score"`
+}
+
+// writeJSONHotspots is a function that writes a hotspot report as a versioned JSON document, with the same schema version as the JSON report.
+func writeJSONHotspots(w io.Writer, r *hotspotReport) error {
+	doc := &jsonHotspotReport{
+		SchemaVersion: jsonSchemaVersion,
+		LoccVersion:   version,
+		ConfigHash:    r.ConfigHash,
+		Revision:      r.Revision,
+		Score:         r.Score,
+		Files:         make([]jsonHotspot, 0, len(r.Files)),
+	}
+	if !r.Since.IsZero() {
+		doc.Since = r.Since.Format(time.RFC3339)
+	}
+	for i, file := range r.Files {
+		doc.Files = append(doc.Files, jsonHotspot{
+			Rank:     i + 1,
+			Path:     file.Path,
+			Language: file.Language,
+			Category: file.Category,
+			Blank:    file.Blank,
+			Comment:  file.Comment,
+			Code:     file.Code,
+			Commits:  file.Commits,
+			Added:    file.Added,
+			Removed:  file.Removed,
+			Score:    file.Score,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writeCSVHotspots is a function that writes a hotspot report as comma-separated values, see writeDelimitedHotspots.
+func writeCSVHotspots(w io.Writer, r *hotspotReport) error {
+	return writeDelimitedHotspots(w, r, ',')
+}
+
+// writeTSVHotspots is a function that writes a hotspot report as tab-separated values, see writeDelimitedHotspots.
+func writeTSVHotspots(w io.Writer, r *hotspotReport) error {
+	return writeDelimitedHotspots(w, r, '\t')
+}
+
+// writeDelimitedHotspots is a function that writes a hotspot report as delimiter-separated values, with a header row
+// and one record per file, the highest score first.
+func writeDelimitedHotspots(w io.Writer, r *hotspotReport, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	// RFC 4180 lines end with CRLF
+	writer.UseCRLF = delimiter == ','
+
+	if err := writer.Write([]string{"rank", "path", "language", "category", "code", "comment", "blank", "commits", "added", "removed", "score"}); err != nil {
+		return err
+	}
+	for i, file := range r.Files {
+		record := []string{
+			strconv.Itoa(i + 1),
+			file.Path,
+			file.Language,
+			file.Category,
+			strconv.Itoa(file.Code),
+			strconv.Itoa(file.Comment),
+			strconv.Itoa(file.Blank),
+			strconv.Itoa(file.Commits),
+			strconv.Itoa(file.Added),
+			strconv.Itoa(file.Removed),
+			strconv.FormatInt(file.Score, 10),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
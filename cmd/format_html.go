@@ -0,0 +1,167 @@
+// cmd/format_html.go
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+)
+
+// htmlTemplate is an embedded file system that contains the template of the html output format.
+// The template holds the CSS and JavaScript inline, so the written report is a single self-contained file.
+//
+//go:embed report.html.tmpl
+var htmlTemplate embed.FS
+
+// htmlLargestFiles is the number of files listed in the largest files table of the html output format, unless --top is given.
+const htmlLargestFiles = 10
+
+// htmlChartLanguages is the number of languages drawn in the chart of the html output format,
+// the remaining languages are drawn as a single "other" bar.
+const htmlChartLanguages = 12
+
+// htmlColors are the colors of the bars of the chart, used in turn.
+var htmlColors = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948",
+	"#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#86bcb6", "#d37295",
+}
+
+// Dimensions of the chart of the html output format, in pixels.
+const (
+	htmlChartLabelWidth = 160
+	htmlChartBarWidth   = 400
+	htmlChartRowHeight  = 24
+)
+
+// htmlLanguage is a row of the language table of the html output format.
+type htmlLanguage struct {
+	languageSummary
+
+	// Share is the share of the code lines of the language in the code lines of the report, in percent.
+	Share float64
+}
+
+// htmlBar is a bar of the chart of the html output format.
+type htmlBar struct {
+	Label string
+	Value int
+	Color string
+	Y     int
+	Width int
+}
+
+// htmlReport holds the data the html template is executed with.
+type htmlReport struct {
+	Version      string
+	ConfigHash   string
+	Revision     string
+	Total        languageSummary
+	ShowCategory bool
+	Languages    []htmlLanguage
+	Bars         []htmlBar
+	ChartWidth   int
+	ChartHeight  int
+	LabelWidth   int
+	Tree         *dirNode
+	LargestFiles []fileResult
+
+	// UnownedFiles lists the files without code owner, with --by owner only.
+	UnownedFiles []fileResult
+}
+
+// newHTMLReport is a function that builds the data of the html template from a report.
+func newHTMLReport(r *report) *htmlReport {
+	doc := &htmlReport{
+		Version:      version,
+		ConfigHash:   r.ConfigHash,
+		Revision:     r.Revision,
+		Total:        r.Total,
+		ShowCategory: len(r.Categories) > 1,
+		ChartWidth:   htmlChartLabelWidth + htmlChartBarWidth + 80,
+		LabelWidth:   htmlChartLabelWidth,
+		Tree:         r.dirTree(),
+	}
+
+	// Languages are sorted by code lines, largest first, unless another order is selected
+	languages := arrangeLanguages(r.Languages, sortCode)
+	for _, lang := range languages {
+		row := htmlLanguage{languageSummary: lang}
+		if r.Total.Code > 0 {
+			row.Share = float64(lang.Code) * 100 / float64(r.Total.Code)
+		}
+		doc.Languages = append(doc.Languages, row)
+	}
+
+	// Draw the largest languages, and the remaining languages as a single bar
+	var bars []htmlBar
+	other := 0
+	for i, lang := range languages {
+		if i < htmlChartLanguages {
+			bars = append(bars, htmlBar{Label: lang.Language, Value: lang.Code})
+		} else {
+			other += lang.Code
+		}
+	}
+	if other > 0 {
+		bars = append(bars, htmlBar{Label: "other", Value: other})
+	}
+	largest := 0
+	for _, bar := range bars {
+		if bar.Value > largest {
+			largest = bar.Value
+		}
+	}
+	for i := range bars {
+		bars[i].Color = htmlColors[i%len(htmlColors)]
+		bars[i].Y = i * htmlChartRowHeight
+		if largest > 0 {
+			bars[i].Width = bars[i].Value * htmlChartBarWidth / largest
+		}
+	}
+	doc.Bars = bars
+	doc.ChartHeight = len(bars) * htmlChartRowHeight
+
+	// List the largest files by code lines whatever the order selected, so that the table holds what its title says.
+	// Files with fewer lines than --min-lines are hidden, and --top sets the number of files.
+	var files []fileResult
+	for _, file := range r.Files {
+		if file.Code >= minLines {
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Code > files[j].Code
+	})
+	limit := htmlLargestFiles
+	if topFiles > 0 {
+		limit = topFiles
+	}
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	doc.LargestFiles = files
+
+	// With code owners, list the files without owner
+	if reportBy == byOwner {
+		doc.UnownedFiles = r.unownedFiles()
+	}
+	return doc
+}
+
+// writeHTMLReport is a function that writes a report as a self-contained HTML page, with a sortable language table,
+// a chart of the code lines per language, a collapsible directory tree, the largest files and, with --by owner, the files without code owner.
+func writeHTMLReport(w io.Writer, r *report) error {
+	tmpl, err := template.New("report.html.tmpl").Funcs(template.FuncMap{
+		"percent": func(value float64) string {
+			return fmt.Sprintf("%.1f%%", value)
+		},
+		"base": path.Base,
+	}).ParseFS(htmlTemplate, "report.html.tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to parse html template: %w", err)
+	}
+	return tmpl.Execute(w, newHTMLReport(r))
+}
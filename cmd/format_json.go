@@ -0,0 +1,197 @@
+// cmd/format_json.go
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonSchemaVersion is the version of the JSON report document.
+// It changes only when fields are removed or change meaning, new fields can be added within a version.
+const jsonSchemaVersion = 1
+
+// jsonReport is the document written by the json output format.
+type jsonReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	LoccVersion   string         `json:"locc_version"`
+	ConfigHash    string         `json:"config_hash"`
+	Revision      string         `json:"revision,omitempty"`
+	Files         []jsonFile     `json:"files"`
+	Languages     []jsonLanguage `json:"languages"`
+	Totals        jsonTotals     `json:"totals"`
+
+	// Directories holds the directory tree, written with --by directory only.
+	Directories *jsonDirectory `json:"directories,omitempty"`
+
+	// Owners holds the lines attributed to every owner per language, written with --by author, --by email-domain and --by owner only.
+	Owners []jsonOwner `json:"owners,omitempty"`
+
+	// UnownedFiles lists the paths of the files without code owner, written with --by owner only.
+	UnownedFiles []string `json:"unowned_files,omitempty"`
+}
+
+// jsonFile is the record of a single file in the JSON report.
+type jsonFile struct {
+	Path      string `json:"path"`
+	Language  string `json:"language"`
+	Category  string `json:"category"`
+	Blank     int    `json:"blank"`
+	Comment   int    `json:"comment"`
+	Code      int    `json:"code"`
+	Bytes     int64  `json:"bytes"`
+	Generated bool   `json:"generated"`
+}
+
+// jsonLanguage is the aggregate of one language in the JSON report.
+type jsonLanguage struct {
+	Language  string `json:"language"`
+	Category  string `json:"category"`
+	Files     int    `json:"files"`
+	Blank     int    `json:"blank"`
+	Comment   int    `json:"comment"`
+	Code      int    `json:"code"`
+	Bytes     int64  `json:"bytes"`
+	Generated int    `json:"generated"`
+}
+
+// jsonTotals is the aggregate of all files in the JSON report.
+type jsonTotals struct {
+	Files   int   `json:"files"`
+	Blank   int   `json:"blank"`
+	Comment int   `json:"comment"`
+	Code    int   `json:"code"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// jsonOwner is the lines of one language attributed to an author, an email domain or a code owner in the JSON report.
+type jsonOwner struct {
+	Owner    string `json:"owner"`
+	Language string `json:"language"`
+	Category string `json:"category"`
+	Files    int    `json:"files"`
+	Blank    int    `json:"blank"`
+	Comment  int    `json:"comment"`
+	Code     int    `json:"code"`
+}
+
+// jsonDirectory is a directory of the directory tree in the JSON report, with the totals of every file below it.
+type jsonDirectory struct {
+	Path        string          `json:"path"`
+	Files       int             `json:"files"`
+	Blank       int             `json:"blank"`
+	Comment     int             `json:"comment"`
+	Code        int             `json:"code"`
+	Bytes       int64           `json:"bytes"`
+	Languages   []jsonLanguage  `json:"languages"`
+	Directories []jsonDirectory `json:"directories"`
+}
+
+// newJSONLanguage is a function that converts a language aggregate into its JSON record.
+func newJSONLanguage(lang languageSummary) jsonLanguage {
+	return jsonLanguage{
+		Language:  lang.Language,
+		Category:  lang.Category,
+		Files:     lang.Files,
+		Blank:     lang.Blank,
+		Comment:   lang.Comment,
+		Code:      lang.Code,
+		Bytes:     lang.Bytes,
+		Generated: lang.Generated,
+	}
+}
+
+// newJSONDirectory is a function that converts a directory of the tree into its JSON record.
+// Subdirectories deeper than the maximum depth are left out, their files being part of the totals of their ancestors.
+func newJSONDirectory(dir *dirNode, depth, maxDepth int) jsonDirectory {
+	doc := jsonDirectory{
+		Path:        dir.Path,
+		Files:       dir.Total.Files,
+		Blank:       dir.Total.Blank,
+		Comment:     dir.Total.Comment,
+		Code:        dir.Total.Code,
+		Bytes:       dir.Total.Bytes,
+		Languages:   make([]jsonLanguage, 0, len(dir.Languages)),
+		Directories: []jsonDirectory{},
+	}
+	for _, lang := range dir.Languages {
+		doc.Languages = append(doc.Languages, newJSONLanguage(lang))
+	}
+	if maxDepth > 0 && depth >= maxDepth {
+		return doc
+	}
+	for _, child := range dir.Dirs {
+		doc.Directories = append(doc.Directories, newJSONDirectory(child, depth+1, maxDepth))
+	}
+	return doc
+}
+
+// newJSONReport is a function that converts a report into the JSON report document.
+// Files are sorted by path and languages by name unless another order is selected, and the document holds no timestamps, so the same tree
+// and configuration always produce the same document.
+func newJSONReport(r *report) *jsonReport {
+	doc := &jsonReport{
+		SchemaVersion: jsonSchemaVersion,
+		LoccVersion:   version,
+		ConfigHash:    r.ConfigHash,
+		Revision:      r.Revision,
+		Files:         make([]jsonFile, 0, len(r.Files)),
+		Languages:     make([]jsonLanguage, 0, len(r.Languages)),
+		Totals: jsonTotals{
+			Files:   r.Total.Files,
+			Blank:   r.Total.Blank,
+			Comment: r.Total.Comment,
+			Code:    r.Total.Code,
+			Bytes:   r.Total.Bytes,
+		},
+	}
+	for _, file := range arrangeFiles(r.Files, "", true) {
+		doc.Files = append(doc.Files, jsonFile{
+			Path:      file.Path,
+			Language:  file.Language,
+			Category:  file.Category,
+			Blank:     file.Blank,
+			Comment:   file.Comment,
+			Code:      file.Code,
+			Bytes:     file.Bytes,
+			Generated: file.Generated,
+		})
+	}
+	for _, lang := range arrangeLanguages(r.Languages, "") {
+		doc.Languages = append(doc.Languages, newJSONLanguage(lang))
+	}
+
+	// With the directory granularity, add the directory tree down to the selected depth
+	if reportBy == byDirectory {
+		tree := newJSONDirectory(r.dirTree(), 0, treeDepth)
+		doc.Directories = &tree
+	}
+
+	// With an owner granularity, add the lines of every owner
+	if isOwnerGranularity(reportBy) {
+		doc.Owners = make([]jsonOwner, 0, len(r.Owners))
+		for _, owner := range r.Owners {
+			doc.Owners = append(doc.Owners, jsonOwner{
+				Owner:    owner.Owner,
+				Language: owner.Language,
+				Category: owner.Category,
+				Files:    owner.Files,
+				Blank:    owner.Blank,
+				Comment:  owner.Comment,
+				Code:     owner.Code,
+			})
+		}
+	}
+	if reportBy == byOwner {
+		for _, file := range r.unownedFiles() {
+			doc.UnownedFiles = append(doc.UnownedFiles, file.Path)
+		}
+	}
+	return doc
+}
+
+// writeJSONReport is a function that writes a report as an indented JSON document.
+func writeJSONReport(w io.Writer, r *report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newJSONReport(r))
+}
@@ -0,0 +1,165 @@
+// cmd/format_markdown.go
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeMarkdownReport is a function that writes a report as GitHub-flavoured Markdown, for READMEs and pull requests.
+// It writes a table of the languages, sorted by lines of code in descending order, followed by the totals.
+// --top N adds a table of the N largest files, --by directory adds a table of the totals of every directory, down to --depth,
+// and --by author, email-domain or owner a table of the lines of every owner per language, followed with --by owner by the files without owner.
+func writeMarkdownReport(w io.Writer, r *report) error {
+	withCategory := len(r.Categories) > 1
+
+	// Sort the languages by lines of code, the largest first, unless another order is selected
+	languages := arrangeLanguages(r.Languages, sortCode)
+
+	table := &textTable{columns: []textColumn{{header: "Language"}}}
+	if withCategory {
+		table.columns = append(table.columns, textColumn{header: "Category"})
+	}
+	table.columns = append(table.columns, textColumn{header: "Files", right: true})
+	table.columns = append(table.columns, statsColumns()...)
+	for _, lang := range languages {
+		cells := []string{lang.Language}
+		if withCategory {
+			cells = append(cells, lang.Category)
+		}
+		cells = append(cells, strconv.Itoa(lang.Files))
+		table.addRow(append(cells, statsCells(lang.lineStats)...)...)
+	}
+	footer := []string{"Total"}
+	if withCategory {
+		footer = append(footer, "")
+	}
+	footer = append(footer, strconv.Itoa(r.Total.Files))
+	table.setFooter(append(footer, statsCells(r.Total.lineStats)...)...)
+	if err := writeMarkdownSection(w, "Lines of code", table); err != nil {
+		return err
+	}
+
+	// If requested, write a table of the largest files
+	if topFiles > 0 {
+		files := arrangeFiles(r.Files, sortCode, true)
+		table := &textTable{columns: append([]textColumn{{header: "File"}, {header: "Language"}}, statsColumns()...)}
+		for _, file := range files {
+			table.addRow(append([]string{markdownCode(file.Path), file.Language}, statsCells(file.lineStats)...)...)
+		}
+		if err := writeMarkdownSection(w, fmt.Sprintf("Largest files (top %d)", topFiles), table); err != nil {
+			return err
+		}
+	}
+
+	// If requested, write a table of the totals of every directory
+	if reportBy == byDirectory {
+		table := &textTable{columns: []textColumn{{header: "Directory"}, {header: "Files", right: true}}}
+		table.columns = append(table.columns, statsColumns()...)
+		r.dirTree().walk(treeDepth, func(dir *dirNode, depth int) {
+			cells := []string{markdownCode(dir.Path + "/"), strconv.Itoa(dir.Total.Files)}
+			table.addRow(append(cells, statsCells(dir.Total.lineStats)...)...)
+		})
+		if err := writeMarkdownSection(w, "Directories", table); err != nil {
+			return err
+		}
+	}
+
+	// If requested, write a table of the lines of every owner
+	if isOwnerGranularity(reportBy) {
+		table := &textTable{columns: []textColumn{{header: ownerHeader(reportBy)}, {header: "Language"}, {header: "Files", right: true}}}
+		table.columns = append(table.columns, statsColumns()...)
+		for _, owner := range r.ownerTotals() {
+			name := owner.Owner
+			for _, lang := range owner.Languages {
+				table.addRow(append([]string{name, lang.Language, strconv.Itoa(lang.Files)}, statsCells(lang.lineStats)...)...)
+				name = ""
+			}
+		}
+		if err := writeMarkdownSection(w, "Lines by "+strings.ToLower(ownerHeader(reportBy)), table); err != nil {
+			return err
+		}
+	}
+
+	// With code owners, list the files without owner
+	if files := r.unownedFiles(); reportBy == byOwner && len(files) > 0 {
+		table := &textTable{columns: append([]textColumn{{header: "File"}, {header: "Language"}}, statsColumns()...)}
+		for _, file := range files {
+			table.addRow(append([]string{markdownCode(file.Path), file.Language}, statsCells(file.lineStats)...)...)
+		}
+		if err := writeMarkdownSection(w, "Unowned files", table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdownSection is a function that writes a table under a level 3 heading.
+func writeMarkdownSection(w io.Writer, title string, table *textTable) error {
+	if _, err := fmt.Fprintf(w, "### %s\n\n", title); err != nil {
+		return err
+	}
+	if err := table.renderMarkdown(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// renderMarkdown writes the table as a GitHub-flavoured Markdown table.
+// Right-aligned columns are aligned to the right with a "---:" delimiter, and the footer is written in bold.
+func (t *textTable) renderMarkdown(w io.Writer) error {
+	var b strings.Builder
+	writeRow := func(cells []string, bold bool) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			cell = markdownEscape(cell)
+			if bold && cell != "" {
+				cell = "**" + cell + "**"
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	headers := make([]string, len(t.columns))
+	delimiters := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		headers[i] = column.header
+		delimiters[i] = "---"
+		if column.right {
+			delimiters[i] = "---:"
+		}
+	}
+	writeRow(headers, false)
+	b.WriteString("|" + strings.Join(delimiters, "|") + "|\n")
+	for _, row := range t.rows {
+		writeRow(row, false)
+	}
+	if t.footer != nil {
+		writeRow(t.footer, true)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape is a function that escapes the pipes of a table cell, which would otherwise end the cell.
+func markdownEscape(cell string) string {
+	return strings.ReplaceAll(cell, "|", `\|`)
+}
+
+// markdownCode is a function that formats a path as inline code.
+// Backticks in the path are enclosed in a longer run of backticks, as Markdown requires.
+func markdownCode(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
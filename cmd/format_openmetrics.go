@@ -0,0 +1,109 @@
+// cmd/format_openmetrics.go
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metricLabelPattern matches valid OpenMetrics label names.
+var metricLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedMetricLabels are the labels set by locc, which --label cannot override.
+var reservedMetricLabels = []string{"language", "category", "kind"}
+
+// metricLabel is a label of a metric.
+type metricLabel struct {
+	name  string
+	value string
+}
+
+// parseMetricLabels is a function that parses the name=value pairs given with --label.
+// It returns the labels sorted by name, or an error if a pair is malformed, a name is invalid or reserved, or a name is repeated.
+func parseMetricLabels(pairs []string) ([]metricLabel, error) {
+	var labels []metricLabel
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", pair)
+		}
+		if !metricLabelPattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		for _, reserved := range reservedMetricLabels {
+			if name == reserved {
+				return nil, fmt.Errorf("label %q is set by locc and cannot be overridden", name)
+			}
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("label %q is given more than once", name)
+		}
+		seen[name] = true
+		labels = append(labels, metricLabel{name, value})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels, nil
+}
+
+// formatMetricLabels is a function that formats labels as a label set, escaping backslashes, quotes and line feeds in the values.
+func formatMetricLabels(labels []metricLabel) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = label.name + `="` + escaper.Replace(label.value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// writeOpenMetricsReport is a function that writes a report in the OpenMetrics text format, as gauges per language:
+// locc_lines by kind of line (blank, comment, code), locc_files and locc_bytes.
+// The labels given with --label are added to every sample. The output is also valid Prometheus text format,
+// so it can be written to the directory of the node_exporter textfile collector.
+func writeOpenMetricsReport(w io.Writer, r *report) error {
+	extra, err := parseMetricLabels(metricLabels)
+	if err != nil {
+		return err
+	}
+
+	// labels returns the label set of a sample of a language
+	labels := func(lang languageSummary, more ...metricLabel) string {
+		set := append([]metricLabel{}, extra...)
+		set = append(set, metricLabel{"language", lang.Language}, metricLabel{"category", lang.Category})
+		return formatMetricLabels(append(set, more...))
+	}
+
+	var b strings.Builder
+	writeFamily := func(name, help string, samples func()) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		samples()
+	}
+
+	writeFamily("locc_lines", "Number of lines of the processed files, by kind of line.", func() {
+		for _, lang := range r.Languages {
+			fmt.Fprintf(&b, "locc_lines%s %d\n", labels(lang, metricLabel{"kind", "blank"}), lang.Blank)
+			fmt.Fprintf(&b, "locc_lines%s %d\n", labels(lang, metricLabel{"kind", "comment"}), lang.Comment)
+			fmt.Fprintf(&b, "locc_lines%s %d\n", labels(lang, metricLabel{"kind", "code"}), lang.Code)
+		}
+	})
+	writeFamily("locc_files", "Number of processed files.", func() {
+		for _, lang := range r.Languages {
+			fmt.Fprintf(&b, "locc_files%s %d\n", labels(lang), lang.Files)
+		}
+	})
+	writeFamily("locc_bytes", "Size of the processed files, in bytes.", func() {
+		for _, lang := range r.Languages {
+			fmt.Fprintf(&b, "locc_bytes%s %d\n", labels(lang), lang.Bytes)
+		}
+	})
+	b.WriteString("# EOF\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,176 @@
+// cmd/format_text.go
+package cmd
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeTextReport is a function that writes a report as aligned tables, like cloc and tokei do.
+// It writes one row per language, sorted by lines of code in descending order, followed by the totals.
+// A category column is added when more than one category is enabled, and verbose output adds a table of the files first.
+// With --by directory, the languages are replaced by the directory tree, see writeTextTree, and with --by author, email-domain or owner
+// by the lines of every owner, see writeTextOwners.
+// The tables fit the width of the terminal and are styled only on terminals that accept colors.
+func writeTextReport(w io.Writer, r *report) error {
+	width, color := terminalWidth(w), styler(useColor(w))
+	withCategory := len(r.Categories) > 1
+
+	// If verbose output is enabled, write a table of the processed files
+	if verbose {
+		files := &textTable{columns: []textColumn{{header: "File"}, {header: "Language"}}}
+		if withCategory {
+			files.columns = append(files.columns, textColumn{header: "Category"})
+		}
+		files.columns = append(files.columns, statsColumns()...)
+		for _, file := range arrangeFiles(r.Files, "", true) {
+			cells := []string{file.Path, file.Language}
+			if withCategory {
+				cells = append(cells, file.Category)
+			}
+			files.addRow(append(cells, statsCells(file.lineStats)...)...)
+		}
+		if err := files.render(w, width, color); err != nil {
+			return err
+		}
+	}
+
+	// If the directory granularity is selected, write the directory tree
+	if reportBy == byDirectory {
+		return writeTextTree(w, r, width, color)
+	}
+
+	// If an owner granularity is selected, write the lines of every owner
+	if isOwnerGranularity(reportBy) {
+		return writeTextOwners(w, r, width, color)
+	}
+
+	// Sort the languages by lines of code, the largest first, unless another order is selected
+	languages := arrangeLanguages(r.Languages, sortCode)
+
+	table := &textTable{columns: []textColumn{{header: "Language"}}}
+	if withCategory {
+		table.columns = append(table.columns, textColumn{header: "Category"})
+	}
+	table.columns = append(table.columns, textColumn{header: "Files", right: true})
+	table.columns = append(table.columns, statsColumns()...)
+	for _, lang := range languages {
+		cells := []string{lang.Language}
+		if withCategory {
+			cells = append(cells, lang.Category)
+		}
+		cells = append(cells, strconv.Itoa(lang.Files))
+		table.addRow(append(cells, statsCells(lang.lineStats)...)...)
+	}
+	footer := []string{"Total"}
+	if withCategory {
+		footer = append(footer, "")
+	}
+	footer = append(footer, strconv.Itoa(r.Total.Files))
+	table.setFooter(append(footer, statsCells(r.Total.lineStats)...)...)
+	return table.render(w, width, color)
+}
+
+// writeTextTree is a function that writes the directory tree of a report as an indented table, like du does.
+// Each directory holds the totals of every file below it, followed by its per-language split and its subdirectories.
+// Directories deeper than --depth are aggregated into their ancestors.
+func writeTextTree(w io.Writer, r *report, width int, color styler) error {
+	withCategory := len(r.Categories) > 1
+
+	table := &textTable{columns: []textColumn{{header: "Directory"}}}
+	if withCategory {
+		table.columns = append(table.columns, textColumn{header: "Category"})
+	}
+	table.columns = append(table.columns, textColumn{header: "Files", right: true})
+	table.columns = append(table.columns, statsColumns()...)
+	r.dirTree().walk(treeDepth, func(dir *dirNode, depth int) {
+		indent := strings.Repeat("  ", depth)
+
+		// Write the totals of the directory
+		cells := []string{indent + dir.Name + "/"}
+		if withCategory {
+			cells = append(cells, "")
+		}
+		cells = append(cells, strconv.Itoa(dir.Total.Files))
+		table.addRow(append(cells, statsCells(dir.Total.lineStats)...)...)
+
+		// Write the split of the directory per language
+		for _, lang := range dir.Languages {
+			cells := []string{indent + "  " + lang.Language}
+			if withCategory {
+				cells = append(cells, lang.Category)
+			}
+			cells = append(cells, strconv.Itoa(lang.Files))
+			table.addRow(append(cells, statsCells(lang.lineStats)...)...)
+		}
+	})
+	return table.render(w, width, color)
+}
+
+// writeTextOwners is a function that writes the lines attributed to every owner as an indented table,
+// each owner with its totals followed by its per-language split, the owners with the most lines of code first.
+// With --by owner, a table of the files without code owner follows.
+func writeTextOwners(w io.Writer, r *report, width int, color styler) error {
+	withCategory := len(r.Categories) > 1
+
+	table := &textTable{columns: []textColumn{{header: ownerHeader(reportBy)}}}
+	if withCategory {
+		table.columns = append(table.columns, textColumn{header: "Category"})
+	}
+	table.columns = append(table.columns, textColumn{header: "Files", right: true})
+	table.columns = append(table.columns, statsColumns()...)
+	for _, owner := range r.ownerTotals() {
+		// Write the totals of the owner
+		cells := []string{owner.Owner}
+		if withCategory {
+			cells = append(cells, "")
+		}
+		cells = append(cells, strconv.Itoa(owner.Total.Files))
+		table.addRow(append(cells, statsCells(owner.Total.lineStats)...)...)
+
+		// Write the split of the owner per language
+		for _, lang := range owner.Languages {
+			cells := []string{"  " + lang.Language}
+			if withCategory {
+				cells = append(cells, lang.Category)
+			}
+			cells = append(cells, strconv.Itoa(lang.Files))
+			table.addRow(append(cells, statsCells(lang.lineStats)...)...)
+		}
+	}
+	footer := []string{"Total"}
+	if withCategory {
+		footer = append(footer, "")
+	}
+	footer = append(footer, strconv.Itoa(r.Total.Files))
+	table.setFooter(append(footer, statsCells(r.Total.lineStats)...)...)
+	if err := table.render(w, width, color); err != nil {
+		return err
+	}
+
+	// List the files without code owner
+	files := r.unownedFiles()
+	if reportBy != byOwner || len(files) == 0 {
+		return nil
+	}
+	unowned := &textTable{columns: append([]textColumn{{header: "Unowned file"}, {header: "Language"}}, statsColumns()...)}
+	for _, file := range files {
+		unowned.addRow(append([]string{file.Path, file.Language}, statsCells(file.lineStats)...)...)
+	}
+	return unowned.render(w, width, color)
+}
+
+// statsColumns is a function that returns the columns of the blank, comment and code line counts.
+func statsColumns() []textColumn {
+	return []textColumn{
+		{header: "Blank", right: true},
+		{header: "Comment", right: true},
+		{header: "Code", right: true},
+	}
+}
+
+// statsCells is a function that returns the cells of the blank, comment and code line counts.
+func statsCells(stats lineStats) []string {
+	return []string{strconv.Itoa(stats.Blank), strconv.Itoa(stats.Comment), strconv.Itoa(stats.Code)}
+}
@@ -0,0 +1,213 @@
+// cmd/format_tokei.go
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// tokeiTotal is the key of the totals in tokei JSON documents.
+const tokeiTotal = "Total"
+
+// categoryEmbedded is the category of the code tokei reports as embedded in files of another language,
+// such as CSS in HTML, when importing tokei documents.
+const categoryEmbedded = "embedded"
+
+// tokeiLanguageNames maps the language names of the default configuration to the names tokei uses,
+// where they differ from the names cloc uses.
+var tokeiLanguageNames = map[string]string{
+	"batch":    "Batch",
+	"makefile": "Makefile",
+	"shell":    "Shell",
+	"tcl":      "Tcl",
+	"vue":      "Vue",
+	"verilog":  "Verilog",
+}
+
+// tokeiLanguageName is a function that returns the name tokei uses for a language.
+func tokeiLanguageName(name string) string {
+	if display, ok := tokeiLanguageNames[name]; ok {
+		return display
+	}
+	return displayLanguage(name)
+}
+
+// loccLanguageName is a function that returns the locc language name of a language named by tokei.
+// Unknown languages are lowercased, with spaces replaced by underscores.
+func loccLanguageName(name string) string {
+	for lang, display := range tokeiLanguageNames {
+		if display == name {
+			return lang
+		}
+	}
+	for lang, display := range languageDisplayNames {
+		if display == name {
+			return lang
+		}
+	}
+	return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+}
+
+// tokeiStats holds the line counts of a file in a tokei document.
+// Blobs holds the counts of the code embedded in the file, keyed by language.
+type tokeiStats struct {
+	Blanks   int                   `json:"blanks"`
+	Code     int                   `json:"code"`
+	Comments int                   `json:"comments"`
+	Blobs    map[string]tokeiStats `json:"blobs"`
+}
+
+// tokeiReport is the report of a single file in a tokei document.
+type tokeiReport struct {
+	Name  string     `json:"name"`
+	Stats tokeiStats `json:"stats"`
+}
+
+// tokeiLanguage is the entry of one language in a tokei document.
+// Children holds the reports of the code embedded in files of the language, keyed by the embedded language.
+type tokeiLanguage struct {
+	Blanks     int                      `json:"blanks"`
+	Code       int                      `json:"code"`
+	Comments   int                      `json:"comments"`
+	Reports    []tokeiReport            `json:"reports"`
+	Children   map[string][]tokeiReport `json:"children"`
+	Inaccurate bool                     `json:"inaccurate"`
+}
+
+// newTokeiReport is a function that converts a file result into a tokei file report.
+// tokei names files by their path from the counted directory, so the path is prefixed with "./".
+func newTokeiReport(file fileResult) tokeiReport {
+	return tokeiReport{
+		Name: "./" + file.Path,
+		Stats: tokeiStats{
+			Blanks:   file.Blank,
+			Code:     file.Code,
+			Comments: file.Comment,
+			Blobs:    map[string]tokeiStats{},
+		},
+	}
+}
+
+// writeTokeiJSONReport is a function that writes a report in the format of tokei --output json.
+// The document maps each language to its totals and file reports, and holds the totals of all languages under "Total".
+// Languages sharing a tokei name across categories are merged. locc does not detect embedded code, so the children
+// of the languages and the blobs of the files are empty.
+func writeTokeiJSONReport(w io.Writer, r *report) error {
+	doc := make(map[string]*tokeiLanguage)
+	total := &tokeiLanguage{Reports: []tokeiReport{}, Children: map[string][]tokeiReport{}}
+	for _, file := range r.Files {
+		name := tokeiLanguageName(file.Language)
+		lang, ok := doc[name]
+		if !ok {
+			lang = &tokeiLanguage{Children: map[string][]tokeiReport{}}
+			doc[name] = lang
+		}
+		report := newTokeiReport(file)
+		lang.Reports = append(lang.Reports, report)
+		lang.Blanks += file.Blank
+		lang.Code += file.Code
+		lang.Comments += file.Comment
+
+		// tokei lists the reports of every language in the children of the totals
+		total.Children[name] = append(total.Children[name], report)
+		total.Blanks += file.Blank
+		total.Code += file.Code
+		total.Comments += file.Comment
+	}
+	doc[tokeiTotal] = total
+
+	// encoding/json sorts map keys, so the encoding is deterministic
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// readTokeiReport is a function that reads a tokei JSON document and converts it into a report.
+// Files are assigned the category of their language in the default configuration, and the code embedded
+// in other files is assigned to its own language in the "embedded" category. Sizes and generated markers
+// are not part of tokei documents, so they are left empty.
+func readTokeiReport(filename string) (*report, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokei report: %w", err)
+	}
+	var doc map[string]tokeiLanguage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse tokei report: %w", err)
+	}
+
+	// Find the category of the languages in the default configuration
+	defaults, err := retrieveDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	categoryOf := func(lang string) string {
+		if _, ok := defaults.Stores[lang]; ok {
+			return categoryStores
+		}
+		if _, ok := defaults.Documents[lang]; ok {
+			return categoryDocuments
+		}
+		return categoryLanguages
+	}
+
+	// newFile converts a tokei file report into a file result
+	newFile := func(report tokeiReport, lang, category string) fileResult {
+		return fileResult{
+			Path:     path.Clean(strings.TrimPrefix(filepathToSlash(report.Name), "./")),
+			Language: lang,
+			Category: category,
+			lineStats: lineStats{
+				Blank:   report.Stats.Blanks,
+				Comment: report.Stats.Comments,
+				Code:    report.Stats.Code,
+			},
+		}
+	}
+
+	var files []fileResult
+	for name, entry := range doc {
+		// The totals are recomputed from the files
+		if name == tokeiTotal {
+			continue
+		}
+		lang := loccLanguageName(name)
+		for _, report := range entry.Reports {
+			files = append(files, newFile(report, lang, categoryOf(lang)))
+		}
+		for childName, reports := range entry.Children {
+			for _, report := range reports {
+				files = append(files, newFile(report, loccLanguageName(childName), categoryEmbedded))
+			}
+		}
+	}
+
+	r := newReport(files)
+	// Record the categories of the files, built-in categories first
+	seen := make(map[string]bool)
+	for _, file := range files {
+		seen[file.Category] = true
+	}
+	for _, name := range append(append([]string{}, builtinCategories...), categoryEmbedded) {
+		if seen[name] {
+			r.Categories = append(r.Categories, name)
+		}
+	}
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		if r.Files[i].Path != r.Files[j].Path {
+			return r.Files[i].Path < r.Files[j].Path
+		}
+		return r.Files[i].Language < r.Files[j].Language
+	})
+	return r, nil
+}
+
+// filepathToSlash is a function that converts the backslashes of paths recorded on Windows to forward slashes.
+func filepathToSlash(name string) string {
+	return strings.ReplaceAll(name, `\`, "/")
+}
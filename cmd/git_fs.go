@@ -0,0 +1,99 @@
+// cmd/git_fs.go
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// trackedFS is a view of a directory limited to a list of files, such as the files tracked by git.
+// Directory listings only hold the listed files and the directories leading to them, while opening a file
+// is left to the underlying file system, so configuration files are read even if they are not listed.
+type trackedFS struct {
+	base fs.FS
+
+	// dirs maps every directory leading to a listed file to the names of its listed entries, sorted.
+	dirs map[string][]string
+}
+
+// newTrackedFS is a function that creates a view of a file system limited to a list of slash-separated file paths.
+func newTrackedFS(base fs.FS, files []string) *trackedFS {
+	children := map[string]map[string]bool{".": {}}
+	for _, file := range files {
+		// Record the file in its directory, and every directory in its parent
+		for name := path.Clean(file); name != "."; name = path.Dir(name) {
+			dir := path.Dir(name)
+			if children[dir] == nil {
+				children[dir] = make(map[string]bool)
+			}
+			children[dir][path.Base(name)] = true
+		}
+	}
+
+	t := &trackedFS{base: base, dirs: make(map[string][]string, len(children))}
+	for dir, names := range children {
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		t.dirs[dir] = sorted
+	}
+	return t
+}
+
+// Open opens a file of the underlying file system.
+func (t *trackedFS) Open(name string) (fs.File, error) {
+	return t.base.Open(name)
+}
+
+// Stat returns the file info of a file of the underlying file system.
+func (t *trackedFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(t.base, name)
+}
+
+// ReadDir returns the listed entries of a directory, sorted by name.
+// Listed files missing from the underlying file system, such as files deleted but not yet committed, are left out.
+func (t *trackedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	names, ok := t.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		info, err := fs.Stat(t.base, path.Join(name, child))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+// openSourceFS is a function that opens the files to process in a directory.
+// With --rev, the files are read from the git object database as they are at the revision, see openRevisionFS.
+// With --git, only the files tracked by git are listed, see listTrackedFiles. Otherwise every file of the directory is.
+// File systems implementing io.Closer must be closed once processed.
+func openSourceFS(dir string) (fs.FS, error) {
+	if revision != "" {
+		return openRevisionFS(dir, revision, submoduleMode)
+	}
+
+	base := os.DirFS(dir)
+	if !gitMode {
+		return base, nil
+	}
+
+	files, err := listTrackedFiles(dir, submoduleMode)
+	if err != nil {
+		return nil, err
+	}
+	return newTrackedFS(base, files), nil
+}
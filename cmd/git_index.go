@@ -0,0 +1,348 @@
+// cmd/git_index.go
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Values of the --submodules flag.
+const (
+	submodulesSkip    = "skip"
+	submodulesRecurse = "recurse"
+)
+
+// File modes of git index entries and tree entries, masked with gitModeTypeMask.
+const (
+	gitModeTypeMask = 0170000
+	gitModeDir      = 0040000
+	gitModeFile     = 0100000
+	gitModeSymlink  = 0120000
+	gitModeGitlink  = 0160000
+)
+
+// errUnsupportedIndex reports an index the native parser cannot read, such as a split or sparse index.
+// The file list is then read with the git binary instead.
+var errUnsupportedIndex = errors.New("unsupported git index")
+
+// indexEntry is an entry of the git index: a file staged at a path.
+type indexEntry struct {
+	// Path is the slash-separated path of the file relative to the root of the working tree.
+	Path string
+
+	// Mode is the file mode of the entry, which tells regular files from symbolic links and submodules.
+	Mode uint32
+
+	// Hash is the object name of the content of the file, or the commit of a submodule.
+	Hash [20]byte
+}
+
+// findGitDir is a function that finds the git repository containing a directory.
+// It looks for a .git directory, or a .git file pointing to the repository as in worktrees and submodules,
+// in the directory and its parents.
+// It returns the root of the working tree and the git directory, or an error if the directory is not in a git repository.
+func findGitDir(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+	for {
+		gitDir, ok, err := readGitLink(dir)
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			return dir, gitDir, nil
+		}
+
+		// Look in the parent directory, until the root of the file system
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("not a git repository (or any of the parent directories)")
+		}
+		dir = parent
+	}
+}
+
+// readGitLink is a function that returns the git directory of a working tree root, if the directory is one.
+// The .git entry of the directory is either the git directory itself, or a file holding "gitdir: <path>".
+func readGitLink(dir string) (string, bool, error) {
+	dotGit := filepath.Join(dir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", false, nil
+	}
+	if info.IsDir() {
+		return dotGit, true, nil
+	}
+
+	// A .git file points to the git directory, relative to the working tree
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", dotGit, err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", false, fmt.Errorf("invalid git file %s", dotGit)
+	}
+	gitDir = filepath.FromSlash(strings.TrimSpace(gitDir))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return gitDir, true, nil
+}
+
+// readGitIndex is a function that reads the index of a git directory.
+// A repository without an index, such as a new one, has no entries.
+func readGitIndex(gitDir string) ([]indexEntry, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "index"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git index: %w", err)
+	}
+	return parseGitIndex(data)
+}
+
+// parseGitIndex is a function that parses the entries of a git index file, in version 2, 3 or 4.
+// Only the entries at stage 0 are returned, or the first stage of unmerged paths.
+// It returns errUnsupportedIndex for split and sparse indexes, whose entries are not all in the file.
+func parseGitIndex(data []byte) ([]indexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("invalid git index signature")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("%w: version %d", errUnsupportedIndex, version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	// entryHeaderSize is the size of the fixed part of an entry: stat data, object name and flags
+	const entryHeaderSize = 62
+	truncated := fmt.Errorf("truncated git index")
+
+	entries := make([]indexEntry, 0, count)
+	pos := 12
+	previous := ""
+	for i := uint32(0); i < count; i++ {
+		if pos+entryHeaderSize > len(data) {
+			return nil, truncated
+		}
+		var entry indexEntry
+		entry.Mode = binary.BigEndian.Uint32(data[pos+24 : pos+28])
+		copy(entry.Hash[:], data[pos+40:pos+60])
+		flags := binary.BigEndian.Uint16(data[pos+60 : pos+62])
+		next := pos + entryHeaderSize
+
+		// Version 3 and later have extended flags when the extended bit is set
+		if version >= 3 && flags&0x4000 != 0 {
+			next += 2
+		}
+		if next > len(data) {
+			return nil, truncated
+		}
+
+		if version == 4 {
+			// The path is compressed: the number of bytes to remove from the previous path, then the suffix to append
+			strip, n := readOffsetVarint(data[next:])
+			if n == 0 || strip > uint64(len(previous)) {
+				return nil, truncated
+			}
+			next += n
+			end := bytes.IndexByte(data[next:], 0)
+			if end < 0 {
+				return nil, truncated
+			}
+			entry.Path = previous[:len(previous)-int(strip)] + string(data[next:next+end])
+			pos = next + end + 1
+		} else {
+			// The path is NUL-terminated, and the entry padded with NULs to a multiple of 8 bytes
+			end := bytes.IndexByte(data[next:], 0)
+			if end < 0 {
+				return nil, truncated
+			}
+			entry.Path = string(data[next : next+end])
+			pos += (next - pos + end + 8) &^ 7
+		}
+		previous = entry.Path
+
+		// Sparse indexes hold directories instead of the files below them
+		if entry.Mode&gitModeTypeMask == gitModeDir {
+			return nil, fmt.Errorf("%w: sparse index", errUnsupportedIndex)
+		}
+		// Unmerged paths have an entry per stage, keep the first one
+		if len(entries) > 0 && entries[len(entries)-1].Path == entry.Path {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	// Split indexes keep most entries in a shared index, named by the "link" extension
+	for pos+8 <= len(data) {
+		signature := string(data[pos : pos+4])
+		size := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		if signature == "link" || signature == "sdir" {
+			return nil, fmt.Errorf("%w: %s extension", errUnsupportedIndex, signature)
+		}
+		if size < 0 || pos+8+size > len(data) {
+			break
+		}
+		pos += 8 + size
+	}
+	return entries, nil
+}
+
+// readOffsetVarint is a function that decodes a variable-length integer in the offset encoding of git,
+// used by version 4 indexes and by the offset deltas of pack files.
+// It returns the value and the number of bytes read, or 0 bytes if the data is truncated.
+func readOffsetVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	c := data[0]
+	value := uint64(c & 0x7f)
+	n := 1
+	for c&0x80 != 0 {
+		if n >= len(data) {
+			return 0, 0
+		}
+		c = data[n]
+		n++
+		value = ((value + 1) << 7) | uint64(c&0x7f)
+	}
+	return value, n
+}
+
+// checkSubmoduleMode is a function that checks the value of the --submodules flag.
+func checkSubmoduleMode(submodules string) error {
+	if submodules != submodulesSkip && submodules != submodulesRecurse {
+		return fmt.Errorf("unknown submodules mode %q, expected %s or %s", submodules, submodulesSkip, submodulesRecurse)
+	}
+	return nil
+}
+
+// listTrackedFiles is a function that lists the files tracked by git in a directory.
+// It reads the index of the repository containing the directory, and falls back to 'git ls-files' when the index
+// cannot be parsed natively. Submodules are skipped, or their tracked files listed as well with the recurse mode.
+// Symbolic links are skipped, like the directories they may point to.
+// It returns the slash-separated paths of the files relative to the directory.
+func listTrackedFiles(dir, submodules string) ([]string, error) {
+	if err := checkSubmoduleMode(submodules); err != nil {
+		return nil, err
+	}
+
+	worktree, gitDir, err := findGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := listRepositoryFiles(worktree, gitDir, submodules)
+	if errors.Is(err, errUnsupportedIndex) {
+		return gitLsFiles(dir, submodules)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the files below the directory, relative to it
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+	prefix, err := filepath.Rel(worktree, absDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get path of %s in the repository: %w", dir, err)
+	}
+	if prefix == "." {
+		return files, nil
+	}
+	prefix = filepath.ToSlash(prefix) + "/"
+	var below []string
+	for _, file := range files {
+		if rest, ok := strings.CutPrefix(file, prefix); ok {
+			below = append(below, rest)
+		}
+	}
+	return below, nil
+}
+
+// listRepositoryFiles is a function that lists the files in the index of a repository, relative to the root of its working tree.
+func listRepositoryFiles(worktree, gitDir, submodules string) ([]string, error) {
+	entries, err := readGitIndex(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		switch entry.Mode & gitModeTypeMask {
+		case gitModeFile:
+			files = append(files, entry.Path)
+		case gitModeGitlink:
+			if submodules != submodulesRecurse {
+				continue
+			}
+			// Only submodules checked out in the working tree have files to count
+			subdir := filepath.Join(worktree, filepath.FromSlash(entry.Path))
+			subGitDir, ok, err := readGitLink(subdir)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			subFiles, err := listRepositoryFiles(subdir, subGitDir, submodules)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range subFiles {
+				files = append(files, entry.Path+"/"+file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// gitLsFiles is a function that lists the files tracked by git in a directory with the git binary.
+// It is used for the indexes the native parser does not support.
+func gitLsFiles(dir, submodules string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("the git index of %s can only be read with the git binary, which was not found: %w", dir, err)
+	}
+
+	// List the entries with their modes, to skip symbolic links and submodules like the native parser does
+	args := []string{"-C", dir, "ls-files", "-z", "--stage"}
+	if submodules == submodulesRecurse {
+		args = append(args, "--recurse-submodules")
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files tracked by git: %w", err)
+	}
+
+	// Every entry is "<mode> <object> <stage>\t<path>"
+	var files []string
+	for _, record := range strings.Split(string(output), "\x00") {
+		info, file, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+		mode, err := strconv.ParseUint(strings.Fields(info)[0], 8, 32)
+		if err != nil || mode&gitModeTypeMask != gitModeFile {
+			continue
+		}
+		// Unmerged paths have an entry per stage, keep the first one
+		if len(files) > 0 && files[len(files)-1] == file {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
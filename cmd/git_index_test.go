@@ -0,0 +1,199 @@
+// cmd/git_index_test.go
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// indexHeader returns the header of a git index of a version with a number of entries.
+func indexHeader(version, count uint32) []byte {
+	data := []byte("DIRC")
+	data = binary.BigEndian.AppendUint32(data, version)
+	return binary.BigEndian.AppendUint32(data, count)
+}
+
+// indexEntryHeader returns the fixed part of an index entry: zeroed stat data, the mode, an object name filled with a byte and the flags.
+// The extended flags, when the flags have the extended bit, are appended as two zero bytes.
+func indexEntryHeader(mode uint32, fill byte, flags uint16) []byte {
+	data := make([]byte, 24)
+	data = binary.BigEndian.AppendUint32(data, mode)
+	data = append(data, make([]byte, 12)...)
+	data = append(data, bytes.Repeat([]byte{fill}, 20)...)
+	data = binary.BigEndian.AppendUint16(data, flags)
+	if flags&0x4000 != 0 {
+		data = append(data, 0, 0)
+	}
+	return data
+}
+
+// indexEntryPath returns the NUL-padded path of a version 2 or 3 entry, whose fixed part is headerSize bytes long.
+func indexEntryPath(headerSize int, name string) []byte {
+	padding := (headerSize+len(name)+8)&^7 - headerSize - len(name)
+	return append([]byte(name), make([]byte, padding)...)
+}
+
+// join concatenates byte slices.
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestParseGitIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		paths []string
+	}{
+		{
+			name: "version 2",
+			data: join(indexHeader(2, 2),
+				indexEntryHeader(gitModeFile|0644, 1, 4), indexEntryPath(62, "a.go"),
+				indexEntryHeader(gitModeFile|0755, 2, 8), indexEntryPath(62, "dir/b.sh")),
+			paths: []string{"a.go", "dir/b.sh"},
+		},
+		{
+			// An entry whose length is a multiple of 8 is still followed by a full block of NULs
+			name: "version 2 padding",
+			data: join(indexHeader(2, 2),
+				indexEntryHeader(gitModeFile|0644, 1, 2), indexEntryPath(62, "ab"),
+				indexEntryHeader(gitModeFile|0644, 2, 10), indexEntryPath(62, "abcdefghij")),
+			paths: []string{"ab", "abcdefghij"},
+		},
+		{
+			name: "version 3 extended flags",
+			data: join(indexHeader(3, 3),
+				indexEntryHeader(gitModeFile|0644, 1, 4), indexEntryPath(62, "a.go"),
+				indexEntryHeader(gitModeFile|0644, 2, 0x4000|4), indexEntryPath(64, "b.go"),
+				indexEntryHeader(gitModeFile|0644, 3, 4), indexEntryPath(62, "c.go")),
+			paths: []string{"a.go", "b.go", "c.go"},
+		},
+		{
+			name: "version 4 prefix compression",
+			data: join(indexHeader(4, 4),
+				indexEntryHeader(gitModeFile|0644, 1, 8), []byte{0}, []byte("dir/a.go\x00"),
+				indexEntryHeader(gitModeFile|0644, 2, 8), []byte{4}, []byte("b.go\x00"),
+				indexEntryHeader(gitModeFile|0644, 3, 0x4000|12), []byte{4}, []byte("sub/c.go\x00"),
+				indexEntryHeader(gitModeFile|0644, 4, 5), []byte{12}, []byte("x.txt\x00")),
+			paths: []string{"dir/a.go", "dir/b.go", "dir/sub/c.go", "x.txt"},
+		},
+		{
+			name: "unmerged stages",
+			data: join(indexHeader(2, 3),
+				indexEntryHeader(gitModeFile|0644, 1, 0x1000|4), indexEntryPath(62, "a.go"),
+				indexEntryHeader(gitModeFile|0644, 2, 0x2000|4), indexEntryPath(62, "a.go"),
+				indexEntryHeader(gitModeFile|0644, 3, 4), indexEntryPath(62, "b.go")),
+			paths: []string{"a.go", "b.go"},
+		},
+		{
+			name: "extensions",
+			data: join(indexHeader(2, 1),
+				indexEntryHeader(gitModeFile|0644, 1, 4), indexEntryPath(62, "a.go"),
+				[]byte("TREE"), []byte{0, 0, 0, 2}, []byte{0, 0}),
+			paths: []string{"a.go"},
+		},
+		{
+			name:  "empty",
+			data:  indexHeader(2, 0),
+			paths: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := parseGitIndex(test.data)
+			if err != nil {
+				t.Fatalf("parseGitIndex() error = %v", err)
+			}
+			if len(entries) != len(test.paths) {
+				t.Fatalf("parseGitIndex() returned %d entries, want %d", len(entries), len(test.paths))
+			}
+			for i, entry := range entries {
+				if entry.Path != test.paths[i] {
+					t.Errorf("entry %d path = %q, want %q", i, entry.Path, test.paths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitIndexModeAndHash(t *testing.T) {
+	data := join(indexHeader(2, 1), indexEntryHeader(gitModeSymlink, 0xab, 4), indexEntryPath(62, "link"))
+	entries, err := parseGitIndex(data)
+	if err != nil {
+		t.Fatalf("parseGitIndex() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("parseGitIndex() returned %d entries, want 1", len(entries))
+	}
+	if entries[0].Mode != gitModeSymlink {
+		t.Errorf("mode = %o, want %o", entries[0].Mode, gitModeSymlink)
+	}
+	if want := bytes.Repeat([]byte{0xab}, 20); !bytes.Equal(entries[0].Hash[:], want) {
+		t.Errorf("hash = %x, want %x", entries[0].Hash, want)
+	}
+}
+
+func TestParseGitIndexErrors(t *testing.T) {
+	entry := join(indexEntryHeader(gitModeFile|0644, 1, 4), indexEntryPath(62, "a.go"))
+	tests := []struct {
+		name        string
+		data        []byte
+		unsupported bool
+	}{
+		{name: "short header", data: []byte("DIRC\x00\x00")},
+		{name: "bad signature", data: join([]byte("DIRX"), indexHeader(2, 0)[4:])},
+		{name: "version 1", data: indexHeader(1, 0), unsupported: true},
+		{name: "version 5", data: indexHeader(5, 0), unsupported: true},
+		{name: "missing entry", data: indexHeader(2, 1)},
+		{name: "truncated entry", data: join(indexHeader(2, 1), entry[:40])},
+		{name: "unterminated path", data: join(indexHeader(2, 1), indexEntryHeader(gitModeFile|0644, 1, 4), []byte("a.go"))},
+		{name: "missing extended flags", data: join(indexHeader(3, 1), indexEntryHeader(gitModeFile|0644, 1, 4)[:60], []byte{0x40, 4})},
+		{name: "version 4 missing prefix", data: join(indexHeader(4, 1), indexEntryHeader(gitModeFile|0644, 1, 4))},
+		{name: "version 4 truncated prefix", data: join(indexHeader(4, 1), indexEntryHeader(gitModeFile|0644, 1, 4), []byte{0x80})},
+		{name: "version 4 prefix longer than previous path", data: join(indexHeader(4, 2),
+			indexEntryHeader(gitModeFile|0644, 1, 4), []byte{0}, []byte("a.go\x00"),
+			indexEntryHeader(gitModeFile|0644, 2, 4), []byte{5}, []byte("b.go\x00"))},
+		{name: "version 4 unterminated path", data: join(indexHeader(4, 1), indexEntryHeader(gitModeFile|0644, 1, 4), []byte{0}, []byte("a.go"))},
+		{name: "sparse directory", data: join(indexHeader(2, 1), indexEntryHeader(gitModeDir, 1, 4), indexEntryPath(62, "dir/")), unsupported: true},
+		{name: "split index", data: join(indexHeader(2, 1), entry, []byte("link"), []byte{0, 0, 0, 20}, make([]byte, 20)), unsupported: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseGitIndex(test.data)
+			if err == nil {
+				t.Fatal("parseGitIndex() error = nil, want an error")
+			}
+			if errors.Is(err, errUnsupportedIndex) != test.unsupported {
+				t.Errorf("parseGitIndex() error = %v, unsupported = %v, want %v", err, errors.Is(err, errUnsupportedIndex), test.unsupported)
+			}
+		})
+	}
+}
+
+func TestReadOffsetVarint(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		value uint64
+		n     int
+	}{
+		{name: "zero", data: []byte{0x00}, value: 0, n: 1},
+		{name: "one byte", data: []byte{0x7f}, value: 127, n: 1},
+		{name: "two bytes", data: []byte{0x80, 0x00}, value: 128, n: 2},
+		{name: "two bytes with offset", data: []byte{0x81, 0x05}, value: (1+1)<<7 | 5, n: 2},
+		{name: "three bytes", data: []byte{0xff, 0xff, 0x7f}, value: 2113663, n: 3},
+		{name: "trailing data", data: []byte{0x05, 0xff}, value: 5, n: 1},
+		{name: "empty", data: nil, value: 0, n: 0},
+		{name: "truncated", data: []byte{0x80}, value: 0, n: 0},
+		{name: "truncated after two bytes", data: []byte{0x80, 0x80}, value: 0, n: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, n := readOffsetVarint(test.data)
+			if value != test.value || n != test.n {
+				t.Errorf("readOffsetVarint(%x) = %d, %d, want %d, %d", test.data, value, n, test.value, test.n)
+			}
+		})
+	}
+}
@@ -0,0 +1,686 @@
+// cmd/git_objects.go
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of git objects.
+const (
+	objectCommit = "commit"
+	objectTree   = "tree"
+	objectBlob   = "blob"
+	objectTag    = "tag"
+)
+
+// Types of the objects stored in pack files, the deltas being stored against a base object.
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// packObjectTypes maps the types of the objects stored whole in pack files to the types of git objects.
+var packObjectTypes = map[int]string{
+	packCommit: objectCommit,
+	packTree:   objectTree,
+	packBlob:   objectBlob,
+	packTag:    objectTag,
+}
+
+// packCacheSize is the maximum size of the objects kept in memory as bases of deltas, in bytes.
+const packCacheSize = 64 << 20
+
+// maxDeflateRatio is the largest ratio between the inflated and the deflated sizes of a zlib stream.
+// It bounds the size of the content an object can hold, so that a corrupt size is rejected before the content is allocated.
+const maxDeflateRatio = 1032
+
+// maxDeltaDepth is the maximum length of a chain of deltas, the largest depth git packs objects with.
+// A longer chain can only come from deltas based on one another, which would never end.
+const maxDeltaDepth = 4095
+
+// errObjectNotFound reports an object missing from the object database.
+var errObjectNotFound = errors.New("object not found")
+
+// objectHash is the SHA-1 name of a git object.
+type objectHash [20]byte
+
+// String returns the hexadecimal form of the object name.
+func (h objectHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// parseObjectHash is a function that parses the hexadecimal form of an object name.
+func parseObjectHash(s string) (objectHash, bool) {
+	var h objectHash
+	if len(s) != 2*len(h) {
+		return h, false
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, false
+	}
+	return h, true
+}
+
+// objectDB reads the objects of a repository, stored as loose objects or in pack files.
+// Repositories borrowing objects from others with objects/info/alternates are read as well.
+type objectDB struct {
+	// dirs lists the object directories, the repository's own first.
+	dirs []string
+
+	// packs lists the pack files of every object directory.
+	packs []*packFile
+
+	// sizes caches the sizes of the objects, which are asked for every file of every processed revision.
+	sizes map[objectHash]int64
+}
+
+// packFile is a pack file and its index.
+type packFile struct {
+	path string
+	file *os.File
+	size int64
+
+	// fanout holds the number of objects whose name starts with a byte lower than or equal to each value.
+	fanout [256]uint32
+
+	// names holds the sorted object names, offsets their 32-bit offsets in the pack and largeOffsets the 64-bit offsets,
+	// for packs larger than 2 GiB.
+	names        []byte
+	offsets      []byte
+	largeOffsets []byte
+
+	// cache holds the objects recently read at an offset, which are often the bases of other deltas.
+	cache     map[int64]packObject
+	cacheSize int
+}
+
+// packObject is an object read from a pack file.
+type packObject struct {
+	kind string
+	data []byte
+}
+
+// openObjectDB is a function that opens the object database of a git directory.
+// The pack files are opened right away, and closed with the close method.
+func openObjectDB(gitDir string) (*objectDB, error) {
+	db := &objectDB{sizes: make(map[objectHash]int64)}
+	seen := make(map[string]bool)
+
+	// Add the object directory, then the ones it borrows objects from
+	var addDir func(dir string) error
+	addDir = func(dir string) error {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			return nil
+		}
+		seen[dir] = true
+		db.dirs = append(db.dirs, dir)
+
+		packs, err := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(packs)
+		for _, idx := range packs {
+			pack, err := openPackFile(strings.TrimSuffix(idx, ".idx"))
+			if err != nil {
+				return err
+			}
+			db.packs = append(db.packs, pack)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "info", "alternates"))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read alternates of %s: %w", dir, err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line)
+			}
+			if err := addDir(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := addDir(filepath.Join(commonGitDir(gitDir), "objects")); err != nil {
+		db.close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// close closes the pack files of the object database.
+func (db *objectDB) close() {
+	for _, pack := range db.packs {
+		pack.file.Close()
+	}
+}
+
+// readObject is a function that reads an object of the database.
+// It returns the type and the content of the object, or an error wrapping errObjectNotFound if the object is missing.
+func (db *objectDB) readObject(hash objectHash) (string, []byte, error) {
+	return db.readDeltaBase(hash, 0)
+}
+
+// readDeltaBase is a function that reads an object of the database, at a depth of a chain of deltas, 0 for the object asked for.
+func (db *objectDB) readDeltaBase(hash objectHash, depth int) (string, []byte, error) {
+	for _, pack := range db.packs {
+		if offset, ok := pack.find(hash); ok {
+			object, err := pack.readAt(db, offset, depth)
+			// The errors of the bases of deltas are reported once, with the name of the object asked for
+			if err != nil && depth > 0 {
+				return "", nil, err
+			}
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read object %s from %s: %w", hash, pack.path, err)
+			}
+			return object.kind, object.data, nil
+		}
+	}
+	for _, dir := range db.dirs {
+		kind, data, err := readLooseObject(db.loosePath(dir, hash))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		return kind, data, nil
+	}
+	return "", nil, fmt.Errorf("%w: %s", errObjectNotFound, hash)
+}
+
+// readTypedObject is a function that reads an object of the database, and checks that it has the expected type.
+func (db *objectDB) readTypedObject(hash objectHash, kind string) ([]byte, error) {
+	actual, data, err := db.readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if actual != kind {
+		return nil, fmt.Errorf("object %s is a %s, not a %s", hash, actual, kind)
+	}
+	return data, nil
+}
+
+// objectSize is a function that returns the size of the content of an object, without reading all of it when possible.
+func (db *objectDB) objectSize(hash objectHash) (int64, error) {
+	if size, ok := db.sizes[hash]; ok {
+		return size, nil
+	}
+	size, err := db.readObjectSize(hash)
+	if err != nil {
+		return 0, err
+	}
+	db.sizes[hash] = size
+	return size, nil
+}
+
+// readObjectSize is a function that reads the size of the content of an object from its header.
+func (db *objectDB) readObjectSize(hash objectHash) (int64, error) {
+	for _, pack := range db.packs {
+		if offset, ok := pack.find(hash); ok {
+			size, err := pack.sizeAt(offset)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read object %s from %s: %w", hash, pack.path, err)
+			}
+			return size, nil
+		}
+	}
+	for _, dir := range db.dirs {
+		f, err := os.Open(db.loosePath(dir, hash))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		defer f.Close()
+		_, size, _, err := readLooseHeader(f)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		return size, nil
+	}
+	return 0, fmt.Errorf("%w: %s", errObjectNotFound, hash)
+}
+
+// findObjects is a function that lists the objects whose hexadecimal name starts with a prefix, to resolve abbreviated names.
+func (db *objectDB) findObjects(prefix string) ([]objectHash, error) {
+	prefix = strings.ToLower(prefix)
+	found := make(map[objectHash]bool)
+
+	// Loose objects are stored in a directory named by the first two hexadecimal digits
+	for _, dir := range db.dirs {
+		entries, err := os.ReadDir(filepath.Join(dir, prefix[:2]))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, entry := range entries {
+			if hash, ok := parseObjectHash(prefix[:2] + entry.Name()); ok && strings.HasPrefix(hash.String(), prefix) {
+				found[hash] = true
+			}
+		}
+	}
+
+	// Pack indexes are sorted, the matching names follow the first name not lower than the prefix
+	low, err := hex.DecodeString(prefix[:len(prefix)&^1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid object name %q", prefix)
+	}
+	for _, pack := range db.packs {
+		first, count := pack.bucket(low[0])
+		i := first + sort.Search(count, func(i int) bool {
+			return bytes.Compare(pack.name(first+i), low) >= 0
+		})
+		for ; i < first+count; i++ {
+			var hash objectHash
+			copy(hash[:], pack.name(i))
+			if !strings.HasPrefix(hash.String(), prefix) {
+				break
+			}
+			found[hash] = true
+		}
+	}
+
+	hashes := make([]objectHash, 0, len(found))
+	for hash := range found {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	return hashes, nil
+}
+
+// loosePath returns the path of a loose object in an object directory.
+func (db *objectDB) loosePath(dir string, hash objectHash) string {
+	name := hash.String()
+	return filepath.Join(dir, name[:2], name[2:])
+}
+
+// readLooseObject is a function that reads a loose object: a zlib stream holding "<type> <size>\0" followed by the content.
+func readLooseObject(path string) (string, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	kind, size, r, err := readLooseHeader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	if size > info.Size()*maxDeflateRatio {
+		return "", nil, fmt.Errorf("invalid object size %d for a file of %d bytes", size, info.Size())
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", nil, fmt.Errorf("truncated object: %w", err)
+	}
+	return kind, data, nil
+}
+
+// readLooseHeader is a function that reads the type and the size of a loose object.
+// It returns the reader positioned at the start of the content.
+func readLooseHeader(f io.Reader) (string, int64, *bufio.Reader, error) {
+	z, err := zlib.NewReader(f)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	r := bufio.NewReader(z)
+	header, err := r.ReadString(0)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("invalid object header: %w", err)
+	}
+	kind, sizeText, ok := strings.Cut(strings.TrimSuffix(header, "\x00"), " ")
+	size, err := strconv.ParseInt(sizeText, 10, 64)
+	if !ok || err != nil || size < 0 {
+		return "", 0, nil, fmt.Errorf("invalid object header %q", header)
+	}
+	return kind, size, r, nil
+}
+
+// openPackFile is a function that opens a pack file and reads its index, in version 2.
+// It takes the path of the pack without its .pack or .idx extension.
+func openPackFile(base string) (*packFile, error) {
+	idx, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack index: %w", err)
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index %s.idx, only version 2 is supported", base)
+	}
+
+	pack := &packFile{path: base + ".pack", cache: make(map[int64]packObject)}
+	for i := range pack.fanout {
+		pack.fanout[i] = binary.BigEndian.Uint32(idx[8+4*i:])
+	}
+	count := int(pack.fanout[255])
+
+	// The names, the CRC32 checksums and the offsets of the objects follow the fan-out table
+	pos := 8 + 256*4
+	if len(idx) < pos+count*(20+4+4) {
+		return nil, fmt.Errorf("truncated pack index %s.idx", base)
+	}
+	pack.names = idx[pos : pos+count*20]
+	pos += count * (20 + 4)
+	pack.offsets = idx[pos : pos+count*4]
+	pack.largeOffsets = idx[pos+count*4:]
+
+	pack.file, err = os.Open(pack.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pack: %w", err)
+	}
+	info, err := pack.file.Stat()
+	if err != nil {
+		pack.file.Close()
+		return nil, fmt.Errorf("failed to open pack: %w", err)
+	}
+	pack.size = info.Size()
+	return pack, nil
+}
+
+// bucket returns the index and the number of the objects whose name starts with a byte.
+func (p *packFile) bucket(first byte) (int, int) {
+	start := 0
+	if first > 0 {
+		start = int(p.fanout[first-1])
+	}
+	return start, int(p.fanout[first]) - start
+}
+
+// name returns the name of the i-th object of the index.
+func (p *packFile) name(i int) []byte {
+	return p.names[i*20 : (i+1)*20]
+}
+
+// find returns the offset of an object in the pack, if the pack holds it.
+func (p *packFile) find(hash objectHash) (int64, bool) {
+	first, count := p.bucket(hash[0])
+	i := first + sort.Search(count, func(i int) bool {
+		return bytes.Compare(p.name(first+i), hash[:]) >= 0
+	})
+	if i == first+count || !bytes.Equal(p.name(i), hash[:]) {
+		return 0, false
+	}
+
+	// Offsets with the high bit set are indexes in the table of 64-bit offsets
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	large := int(offset&0x7fffffff) * 8
+	if large+8 > len(p.largeOffsets) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.largeOffsets[large:])), true
+}
+
+// readHeader reads the header of the object at an offset: its pack type and size, then the offset of its data.
+func (p *packFile) readHeader(offset int64) (int, int64, int64, error) {
+	var buf [16]byte
+	n, err := p.file.ReadAt(buf[:], offset)
+	if n == 0 {
+		return 0, 0, 0, fmt.Errorf("truncated pack: %w", err)
+	}
+
+	// The type is in bits 4 to 6 of the first byte, the size is little-endian base 128
+	c := buf[0]
+	kind := int(c>>4) & 7
+	size := int64(c & 0x0f)
+	shift := 4
+	i := 1
+	for c&0x80 != 0 {
+		if i >= n {
+			return 0, 0, 0, fmt.Errorf("truncated pack")
+		}
+		// The size must fit in 63 bits
+		if shift > 56 {
+			return 0, 0, 0, fmt.Errorf("invalid object size")
+		}
+		c = buf[i]
+		size |= int64(c&0x7f) << shift
+		shift += 7
+		i++
+	}
+	return kind, size, offset + int64(i), nil
+}
+
+// inflate returns a reader of the zlib stream starting at an offset.
+func (p *packFile) inflate(offset int64) (io.ReadCloser, error) {
+	return zlib.NewReader(bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62)))
+}
+
+// readData reads the inflated data of the object starting at an offset.
+func (p *packFile) readData(offset, size int64) ([]byte, error) {
+	if size > (p.size-offset)*maxDeflateRatio {
+		return nil, fmt.Errorf("invalid object size %d at offset %d of a pack of %d bytes", size, offset, p.size)
+	}
+	z, err := p.inflate(offset)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(z, data); err != nil {
+		return nil, fmt.Errorf("truncated object: %w", err)
+	}
+	return data, nil
+}
+
+// readAt reads the object at an offset of the pack, applying its deltas.
+// The base of a delta is an earlier object of the same pack, or any object of the database for deltas against a named base.
+// depth is the number of deltas already based on the object, a chain longer than maxDeltaDepth is invalid.
+func (p *packFile) readAt(db *objectDB, offset int64, depth int) (packObject, error) {
+	if object, ok := p.cache[offset]; ok {
+		return object, nil
+	}
+	if depth > maxDeltaDepth {
+		return packObject{}, fmt.Errorf("delta chain longer than %d objects", maxDeltaDepth)
+	}
+
+	kind, size, pos, err := p.readHeader(offset)
+	if err != nil {
+		return packObject{}, err
+	}
+
+	var object packObject
+	switch kind {
+	case packCommit, packTree, packBlob, packTag:
+		data, err := p.readData(pos, size)
+		if err != nil {
+			return packObject{}, err
+		}
+		object = packObject{kind: packObjectTypes[kind], data: data}
+
+	case packOfsDelta, packRefDelta:
+		var base packObject
+		if kind == packOfsDelta {
+			// The base is at a negative offset from the delta, in the offset encoding
+			var buf [16]byte
+			n, _ := p.file.ReadAt(buf[:], pos)
+			distance, read := readOffsetVarint(buf[:n])
+			// The base must be before the delta, a distance of 0 pointing at the delta itself
+			if read == 0 || distance == 0 || int64(distance) >= offset {
+				return packObject{}, fmt.Errorf("invalid delta base offset")
+			}
+			pos += int64(read)
+			base, err = p.readAt(db, offset-int64(distance), depth+1)
+		} else {
+			// The base is named by its hash
+			var hash objectHash
+			if _, err := p.file.ReadAt(hash[:], pos); err != nil {
+				return packObject{}, fmt.Errorf("truncated pack: %w", err)
+			}
+			pos += int64(len(hash))
+			base.kind, base.data, err = db.readDeltaBase(hash, depth+1)
+		}
+		if err != nil {
+			return packObject{}, err
+		}
+
+		delta, err := p.readData(pos, size)
+		if err != nil {
+			return packObject{}, err
+		}
+		data, err := applyDelta(base.data, delta)
+		if err != nil {
+			return packObject{}, err
+		}
+		object = packObject{kind: base.kind, data: data}
+
+	default:
+		return packObject{}, fmt.Errorf("unknown pack object type %d", kind)
+	}
+
+	// Keep the object for the deltas based on it, forgetting every object when the cache is full
+	if p.cacheSize+len(object.data) > packCacheSize {
+		p.cache = make(map[int64]packObject)
+		p.cacheSize = 0
+	}
+	p.cache[offset] = object
+	p.cacheSize += len(object.data)
+	return object, nil
+}
+
+// sizeAt returns the size of the object at an offset of the pack.
+// Deltas start with the sizes of their base and of their result, so only the start of the delta is inflated.
+func (p *packFile) sizeAt(offset int64) (int64, error) {
+	kind, size, pos, err := p.readHeader(offset)
+	if err != nil {
+		return 0, err
+	}
+	switch kind {
+	case packCommit, packTree, packBlob, packTag:
+		return size, nil
+	case packOfsDelta:
+		var buf [16]byte
+		n, _ := p.file.ReadAt(buf[:], pos)
+		_, read := readOffsetVarint(buf[:n])
+		if read == 0 {
+			return 0, fmt.Errorf("invalid delta base offset")
+		}
+		pos += int64(read)
+	case packRefDelta:
+		pos += int64(len(objectHash{}))
+	default:
+		return 0, fmt.Errorf("unknown pack object type %d", kind)
+	}
+
+	z, err := p.inflate(pos)
+	if err != nil {
+		return 0, err
+	}
+	defer z.Close()
+	var buf [20]byte
+	n, err := io.ReadFull(z, buf[:])
+	if n == 0 {
+		return 0, fmt.Errorf("truncated delta: %w", err)
+	}
+	header := buf[:n]
+	if _, read := binary.Uvarint(header); read > 0 {
+		if result, read2 := binary.Uvarint(header[read:]); read2 > 0 {
+			return int64(result), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid delta header")
+}
+
+// applyDelta is a function that rebuilds an object from its base and a delta.
+// The delta holds the sizes of the base and of the result, then instructions copying ranges of the base or inserting new data.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	invalid := fmt.Errorf("invalid delta")
+
+	baseSize, n := binary.Uvarint(delta)
+	if n <= 0 || baseSize != uint64(len(base)) {
+		return nil, invalid
+	}
+	delta = delta[n:]
+	resultSize, n := binary.Uvarint(delta)
+	if n <= 0 {
+		return nil, invalid
+	}
+	delta = delta[n:]
+
+	// The result size is not trusted for the allocation, the result grows with the data copied and inserted but never beyond it
+	result := make([]byte, 0, min(resultSize, uint64(len(base)+len(delta))))
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// Copy: the bits of the opcode tell which bytes of the offset and of the size follow
+			var offset, size uint64
+			for i := 0; i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, invalid
+					}
+					offset |= uint64(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, invalid
+					}
+					size |= uint64(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > uint64(len(base)) || uint64(len(result))+size > resultSize {
+				return nil, invalid
+			}
+			result = append(result, base[offset:offset+size]...)
+		case op != 0:
+			// Insert: the opcode is the number of bytes to insert
+			if int(op) > len(delta) || uint64(len(result)+int(op)) > resultSize {
+				return nil, invalid
+			}
+			result = append(result, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, invalid
+		}
+	}
+	if uint64(len(result)) != resultSize {
+		return nil, invalid
+	}
+	return result, nil
+}
@@ -0,0 +1,433 @@
+// cmd/git_objects_test.go
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world\n")
+	large := bytes.Repeat([]byte{'x'}, 0x10000)
+	tests := []struct {
+		name   string
+		base   []byte
+		delta  []byte
+		result string
+	}{
+		{
+			name:   "copy and insert",
+			base:   base,
+			delta:  []byte{12, 18, 0x90, 6, 6, 't', 'h', 'e', 'r', 'e', ' ', 0x91, 6, 6},
+			result: "hello there world\n",
+		},
+		{
+			name:   "insert only",
+			base:   base,
+			delta:  []byte{12, 3, 3, 'a', 'b', 'c'},
+			result: "abc",
+		},
+		{
+			name:   "empty result",
+			base:   base,
+			delta:  []byte{12, 0},
+			result: "",
+		},
+		{
+			// Every offset and size byte present
+			name:   "copy with all bytes",
+			base:   base,
+			delta:  []byte{12, 5, 0xff, 6, 0, 0, 0, 5, 0, 0},
+			result: "world",
+		},
+		{
+			// A copy without size bytes copies 0x10000 bytes
+			name:   "copy of the default size",
+			base:   large,
+			delta:  []byte{0x80, 0x80, 0x04, 0x80, 0x80, 0x04, 0x80},
+			result: string(large),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := applyDelta(test.base, test.delta)
+			if err != nil {
+				t.Fatalf("applyDelta() error = %v", err)
+			}
+			if string(result) != test.result {
+				t.Errorf("applyDelta() = %q, want %q", result, test.result)
+			}
+		})
+	}
+}
+
+func TestApplyDeltaErrors(t *testing.T) {
+	base := []byte("hello world\n")
+	tests := []struct {
+		name  string
+		delta []byte
+	}{
+		{name: "empty", delta: nil},
+		{name: "wrong base size", delta: []byte{11, 3, 3, 'a', 'b', 'c'}},
+		{name: "truncated base size", delta: []byte{0x8c}},
+		{name: "missing result size", delta: []byte{12}},
+		{name: "result too short", delta: []byte{12, 4, 3, 'a', 'b', 'c'}},
+		{name: "result too long", delta: []byte{12, 2, 3, 'a', 'b', 'c'}},
+		{name: "reserved opcode", delta: []byte{12, 1, 0, 'a'}},
+		{name: "truncated insert", delta: []byte{12, 3, 3, 'a', 'b'}},
+		{name: "truncated copy offset", delta: []byte{12, 6, 0x91}},
+		{name: "truncated copy size", delta: []byte{12, 6, 0x91, 6}},
+		{name: "copy out of the base", delta: []byte{12, 6, 0x91, 8, 6}},
+		{name: "copy beyond the result size", delta: []byte{12, 4, 0x91, 6, 6}},
+		{name: "insert beyond the result size", delta: []byte{12, 2, 3, 'a', 'b', 'c'}},
+		{name: "result size out of range", delta: []byte{12, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 3, 'a', 'b', 'c'}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := applyDelta(base, test.delta); err == nil {
+				t.Error("applyDelta() error = nil, want an error")
+			}
+		})
+	}
+}
+
+// testPackIndex returns a pack file holding the index of objects named by their first byte and last byte, at the given offsets.
+// Offsets of 2 GiB and more are stored in the table of 64-bit offsets, like git does.
+func testPackIndex(offsets map[objectHash]int64) *packFile {
+	var hashes []objectHash
+	for hash := range offsets {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	pack := &packFile{}
+	for _, hash := range hashes {
+		for b := int(hash[0]); b < 256; b++ {
+			pack.fanout[b]++
+		}
+		pack.names = append(pack.names, hash[:]...)
+		offset := offsets[hash]
+		if offset < 0x80000000 {
+			pack.offsets = binary.BigEndian.AppendUint32(pack.offsets, uint32(offset))
+			continue
+		}
+		pack.offsets = binary.BigEndian.AppendUint32(pack.offsets, 0x80000000|uint32(len(pack.largeOffsets)/8))
+		pack.largeOffsets = binary.BigEndian.AppendUint64(pack.largeOffsets, uint64(offset))
+	}
+	return pack
+}
+
+// testHash returns an object name starting with a byte and ending with another.
+func testHash(first, last byte) objectHash {
+	var hash objectHash
+	hash[0], hash[19] = first, last
+	return hash
+}
+
+func TestPackFileFind(t *testing.T) {
+	offsets := map[objectHash]int64{
+		testHash(0x00, 1): 12,
+		testHash(0x10, 1): 100,
+		testHash(0x10, 2): 200,
+		testHash(0x10, 3): 5 << 30,
+		testHash(0xff, 1): 0x7fffffff,
+		testHash(0xff, 2): 1 << 40,
+	}
+	pack := testPackIndex(offsets)
+
+	for hash, want := range offsets {
+		offset, ok := pack.find(hash)
+		if !ok || offset != want {
+			t.Errorf("find(%s) = %d, %v, want %d, true", hash, offset, ok, want)
+		}
+	}
+	for _, hash := range []objectHash{testHash(0x00, 0), testHash(0x10, 4), testHash(0x20, 1), testHash(0xff, 0), testHash(0xff, 3)} {
+		if offset, ok := pack.find(hash); ok {
+			t.Errorf("find(%s) = %d, true, want not found", hash, offset)
+		}
+	}
+
+	// A 64-bit offset index beyond the table is invalid
+	pack.largeOffsets = pack.largeOffsets[:8]
+	if offset, ok := pack.find(testHash(0xff, 2)); ok {
+		t.Errorf("find() with a truncated table of 64-bit offsets = %d, true, want not found", offset)
+	}
+	if offset, ok := pack.find(testHash(0x10, 3)); !ok || offset != 5<<30 {
+		t.Errorf("find() = %d, %v, want %d, true", offset, ok, int64(5<<30))
+	}
+}
+
+// testPackObject is an object written to a test pack: a whole object, or a delta against the object at an offset or with a name.
+type testPackObject struct {
+	kind int
+	data []byte
+
+	// size replaces the size of the data in the header of the object when not 0.
+	size int64
+
+	// base is the index of the base object of an offset delta in the pack, and baseHash the name of the base of a reference delta.
+	base     int
+	baseHash objectHash
+}
+
+// writeTestPack writes a pack file and its version 2 index to the object directory of a git directory.
+// The objects are named by the names given, in the order of the objects.
+func writeTestPack(t *testing.T, gitDir string, objects []testPackObject, names []objectHash) {
+	t.Helper()
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(len(objects)))
+	offsets := make([]int64, len(objects))
+	for i, object := range objects {
+		offsets[i] = int64(pack.Len())
+
+		// The type and size header, little-endian base 128 after the first 4 bits
+		size := int64(len(object.data))
+		if object.size != 0 {
+			size = object.size
+		}
+		c := byte(object.kind<<4) | byte(size&0x0f)
+		for size >>= 4; size > 0; size >>= 7 {
+			pack.WriteByte(c | 0x80)
+			c = byte(size & 0x7f)
+		}
+		pack.WriteByte(c)
+
+		switch object.kind {
+		case packOfsDelta:
+			pack.Write(encodeOffsetVarint(uint64(offsets[i] - offsets[object.base])))
+		case packRefDelta:
+			pack.Write(object.baseHash[:])
+		}
+		z := zlib.NewWriter(&pack)
+		z.Write(object.data)
+		z.Close()
+	}
+	sum := sha1.Sum(pack.Bytes())
+	pack.Write(sum[:])
+
+	// The index lists the names sorted, with their CRC32 checksums, left at 0, and offsets
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return bytes.Compare(names[order[a]][:], names[order[b]][:]) < 0
+	})
+	var idx bytes.Buffer
+	idx.Write([]byte{0xff, 't', 'O', 'c', 0, 0, 0, 2})
+	for b := 0; b < 256; b++ {
+		count := uint32(0)
+		for _, name := range names {
+			if int(name[0]) <= b {
+				count++
+			}
+		}
+		binary.Write(&idx, binary.BigEndian, count)
+	}
+	for _, i := range order {
+		idx.Write(names[i][:])
+	}
+	idx.Write(make([]byte, 4*len(names)))
+	for _, i := range order {
+		binary.Write(&idx, binary.BigEndian, uint32(offsets[i]))
+	}
+
+	dir := filepath.Join(gitDir, "objects", "pack")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "pack-test")
+	if err := os.WriteFile(base+".pack", pack.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".idx", idx.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encodeOffsetVarint returns a value in the offset encoding of git, the inverse of readOffsetVarint.
+func encodeOffsetVarint(value uint64) []byte {
+	encoded := []byte{byte(value & 0x7f)}
+	for value >>= 7; value != 0; value >>= 7 {
+		value--
+		encoded = append([]byte{0x80 | byte(value&0x7f)}, encoded...)
+	}
+	return encoded
+}
+
+// testBlobHash returns the name of a blob with the given content.
+func testBlobHash(content string) objectHash {
+	return sha1.Sum([]byte(fmt.Sprintf("blob %d\x00%s", len(content), content)))
+}
+
+func TestPackDeltaChain(t *testing.T) {
+	contents := []string{"hello world\n", "hello there world\n", "hello there world\nbye\n"}
+	names := []objectHash{testBlobHash(contents[0]), testBlobHash(contents[1]), testBlobHash(contents[2])}
+	objects := []testPackObject{
+		{kind: packBlob, data: []byte(contents[0])},
+		// An offset delta against the first object
+		{kind: packOfsDelta, data: []byte{12, 18, 0x90, 6, 6, 't', 'h', 'e', 'r', 'e', ' ', 0x91, 6, 6}, base: 0},
+		// A reference delta against the second object, itself a delta
+		{kind: packRefDelta, data: []byte{18, 22, 0x90, 18, 4, 'b', 'y', 'e', '\n'}, baseHash: names[1]},
+	}
+	gitDir := t.TempDir()
+	writeTestPack(t, gitDir, objects, names)
+
+	db, err := openObjectDB(gitDir)
+	if err != nil {
+		t.Fatalf("openObjectDB() error = %v", err)
+	}
+	defer db.close()
+
+	// Read the objects from the end of the chain, then from the start
+	for _, i := range []int{2, 1, 0, 1, 2} {
+		kind, data, err := db.readObject(names[i])
+		if err != nil {
+			t.Fatalf("readObject(%d) error = %v", i, err)
+		}
+		if kind != objectBlob || string(data) != contents[i] {
+			t.Errorf("readObject(%d) = %s %q, want blob %q", i, kind, data, contents[i])
+		}
+		size, err := db.readObjectSize(names[i])
+		if err != nil {
+			t.Fatalf("readObjectSize(%d) error = %v", i, err)
+		}
+		if size != int64(len(contents[i])) {
+			t.Errorf("readObjectSize(%d) = %d, want %d", i, size, len(contents[i]))
+		}
+	}
+
+	if _, _, err := db.readObject(testBlobHash("missing")); err == nil {
+		t.Error("readObject() of a missing object error = nil, want an error")
+	}
+}
+
+func TestPackDeltaErrors(t *testing.T) {
+	base := "hello world\n"
+	tests := []struct {
+		name    string
+		objects []testPackObject
+	}{
+		{
+			name: "invalid delta",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packOfsDelta, data: []byte{11, 3, 3, 'a', 'b', 'c'}, base: 0},
+			},
+		},
+		{
+			name: "missing reference base",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packRefDelta, data: []byte{12, 3, 3, 'a', 'b', 'c'}, baseHash: testBlobHash("missing")},
+			},
+		},
+		{
+			// An offset delta pointing at itself
+			name: "invalid base offset",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packOfsDelta, data: []byte{12, 3, 3, 'a', 'b', 'c'}, base: 1},
+			},
+		},
+		{
+			name: "reference delta based on itself",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packRefDelta, data: []byte{12, 3, 3, 'a', 'b', 'c'}, baseHash: testBlobHash("delta")},
+			},
+		},
+		{
+			name: "reference deltas based on each other",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packRefDelta, data: []byte{3, 3, 3, 'a', 'b', 'c'}, baseHash: testBlobHash("other")},
+				{kind: packRefDelta, data: []byte{3, 3, 3, 'x', 'y', 'z'}, baseHash: testBlobHash("delta")},
+			},
+		},
+		{
+			name: "object size larger than the pack",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: packBlob, data: []byte("delta"), size: 1 << 40},
+			},
+		},
+		{
+			name: "unknown type",
+			objects: []testPackObject{
+				{kind: packBlob, data: []byte(base)},
+				{kind: 5, data: []byte(base)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []objectHash{testBlobHash(base), testBlobHash("delta"), testBlobHash("other")}[:len(test.objects)]
+			gitDir := t.TempDir()
+			writeTestPack(t, gitDir, test.objects, names)
+
+			db, err := openObjectDB(gitDir)
+			if err != nil {
+				t.Fatalf("openObjectDB() error = %v", err)
+			}
+			defer db.close()
+			if _, _, err := db.readObject(names[1]); err == nil {
+				t.Error("readObject() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestReadLooseObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		data    string
+		invalid bool
+	}{
+		{name: "blob", content: "blob 6\x00hello\n", data: "hello\n"},
+		{name: "empty blob", content: "blob 0\x00", data: ""},
+		{name: "truncated", content: "blob 7\x00hello\n", invalid: true},
+		{name: "size larger than the file", content: "blob 1099511627776\x00hello\n", invalid: true},
+		{name: "size out of range", content: "blob 99999999999999999999\x00hello\n", invalid: true},
+		{name: "missing size", content: "blob\x00hello\n", invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			z := zlib.NewWriter(&buf)
+			z.Write([]byte(test.content))
+			z.Close()
+			path := filepath.Join(t.TempDir(), "object")
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			kind, data, err := readLooseObject(path)
+			if test.invalid {
+				if err == nil {
+					t.Error("readLooseObject() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readLooseObject() error = %v", err)
+			}
+			if kind != objectBlob || string(data) != test.data {
+				t.Errorf("readLooseObject() = %s %q, want blob %q", kind, data, test.data)
+			}
+		})
+	}
+}
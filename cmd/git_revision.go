@@ -0,0 +1,378 @@
+// cmd/git_revision.go
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSymbolicRefs is the maximum number of symbolic references followed to resolve a reference, to stop on loops.
+const maxSymbolicRefs = 10
+
+// gitRepo is a local git repository, read without the git binary.
+type gitRepo struct {
+	// worktree is the root of the working tree and gitDir the git directory.
+	worktree string
+	gitDir   string
+
+	db *objectDB
+}
+
+// commitInfo holds the fields of a commit object used by locc.
+type commitInfo struct {
+	Hash    objectHash
+	Tree    objectHash
+	Parents []objectHash
+
+	// Author is the name and email of the author, and Time the time of the commit.
+	Author string
+	Time   time.Time
+
+	// Subject is the first line of the commit message.
+	Subject string
+}
+
+// openGitRepo is a function that opens the git repository containing a directory.
+// The repository must be closed with the close method.
+func openGitRepo(dir string) (*gitRepo, error) {
+	worktree, gitDir, err := findGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openObjectDB(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	return &gitRepo{worktree: worktree, gitDir: gitDir, db: db}, nil
+}
+
+// close closes the object database of the repository.
+func (repo *gitRepo) close() {
+	repo.db.close()
+}
+
+// commonGitDir is a function that returns the directory holding the objects and references shared by the worktrees of a repository.
+// Linked worktrees have their own git directory, pointing to the common one with a commondir file.
+func commonGitDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := filepath.FromSlash(strings.TrimSpace(string(data)))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
+}
+
+// resolveRevision is a function that resolves a revision to a commit, like 'git rev-parse <rev>^{commit}'.
+// A revision is a full or abbreviated object name, a reference such as HEAD, a branch, a tag or a remote branch,
+// optionally followed by ~N (the N-th first-parent ancestor) and ^N (the N-th parent) suffixes. Annotated tags are peeled to their commit.
+func (repo *gitRepo) resolveRevision(rev string) (objectHash, error) {
+	// Split the name from its suffixes, which reference names cannot contain
+	name, suffixes := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		name, suffixes = rev[:i], rev[i:]
+	}
+	if name == "" || name == "@" {
+		name = "HEAD"
+	}
+
+	hash, err := repo.resolveName(name)
+	if err != nil {
+		return objectHash{}, err
+	}
+	hash, err = repo.peelToCommit(hash)
+	if err != nil {
+		return objectHash{}, fmt.Errorf("invalid revision %q: %w", rev, err)
+	}
+
+	// Apply the suffixes from left to right
+	for suffixes != "" {
+		op := suffixes[0]
+		suffixes = suffixes[1:]
+
+		// ^{} and ^{commit} peel to a commit, which the name already is
+		if op == '^' && strings.HasPrefix(suffixes, "{") {
+			end := strings.IndexByte(suffixes, '}')
+			if end < 0 || (suffixes[1:end] != "" && suffixes[1:end] != objectCommit) {
+				return objectHash{}, fmt.Errorf("invalid revision %q", rev)
+			}
+			suffixes = suffixes[end+1:]
+			continue
+		}
+
+		// Both suffixes take an optional count, 1 by default
+		digits := len(suffixes) - len(strings.TrimLeft(suffixes, "0123456789"))
+		count := 1
+		if digits > 0 {
+			count, err = strconv.Atoi(suffixes[:digits])
+			if err != nil {
+				return objectHash{}, fmt.Errorf("invalid revision %q", rev)
+			}
+			suffixes = suffixes[digits:]
+		}
+
+		if op == '~' {
+			// The count-th ancestor, following first parents
+			for i := 0; i < count; i++ {
+				commit, err := repo.readCommit(hash)
+				if err != nil {
+					return objectHash{}, err
+				}
+				if len(commit.Parents) == 0 {
+					return objectHash{}, fmt.Errorf("invalid revision %q: commit %s has no parent", rev, hash)
+				}
+				hash = commit.Parents[0]
+			}
+			continue
+		}
+
+		// The count-th parent, ^0 being the commit itself
+		if count == 0 {
+			continue
+		}
+		commit, err := repo.readCommit(hash)
+		if err != nil {
+			return objectHash{}, err
+		}
+		if count > len(commit.Parents) {
+			return objectHash{}, fmt.Errorf("invalid revision %q: commit %s has no parent %d", rev, hash, count)
+		}
+		hash = commit.Parents[count-1]
+	}
+	return hash, nil
+}
+
+// resolveName is a function that resolves an object name or a reference name to an object.
+// References are looked up in the same order as git: the name itself, then under refs/, refs/tags/, refs/heads/ and refs/remotes/.
+func (repo *gitRepo) resolveName(name string) (objectHash, error) {
+	if hash, ok := parseObjectHash(name); ok {
+		return hash, nil
+	}
+
+	for _, candidate := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"} {
+		hash, ok, err := repo.readRef(candidate, 0)
+		if err != nil {
+			return objectHash{}, err
+		}
+		if ok {
+			return hash, nil
+		}
+	}
+
+	// Abbreviated object names have at least 4 hexadecimal digits
+	if len(name) >= 4 && strings.Trim(strings.ToLower(name), "0123456789abcdef") == "" {
+		hashes, err := repo.db.findObjects(name)
+		if err != nil {
+			return objectHash{}, err
+		}
+		switch len(hashes) {
+		case 0:
+		case 1:
+			return hashes[0], nil
+		default:
+			return objectHash{}, fmt.Errorf("short object name %s is ambiguous", name)
+		}
+	}
+	return objectHash{}, fmt.Errorf("unknown revision %q", name)
+}
+
+// readRef is a function that reads a reference, following symbolic references.
+// Pseudo references such as HEAD live in the git directory of the worktree, other references in the common git directory,
+// as loose files or in the packed-refs file.
+func (repo *gitRepo) readRef(name string, depth int) (objectHash, bool, error) {
+	if depth > maxSymbolicRefs {
+		return objectHash{}, false, fmt.Errorf("too many levels of symbolic references at %s", name)
+	}
+	if strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		return objectHash{}, false, nil
+	}
+	// Outside of refs/, only upper-case names such as HEAD or ORIG_HEAD are references, other files of the git directory are not
+	if !strings.HasPrefix(name, "refs/") && strings.Trim(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZ_") != "" {
+		return objectHash{}, false, nil
+	}
+
+	dir := commonGitDir(repo.gitDir)
+	if !strings.HasPrefix(name, "refs/") {
+		dir = repo.gitDir
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err == nil {
+		// The first line is the object name, or "ref: <name>" for a symbolic reference
+		line, _, _ := strings.Cut(string(data), "\n")
+		line = strings.TrimSpace(line)
+		if target, ok := strings.CutPrefix(line, "ref:"); ok {
+			return repo.readRef(strings.TrimSpace(target), depth+1)
+		}
+		if len(line) >= 40 {
+			if hash, ok := parseObjectHash(line[:40]); ok {
+				return hash, true, nil
+			}
+		}
+		return objectHash{}, false, fmt.Errorf("invalid reference %s", name)
+	}
+	if !errors.Is(err, os.ErrNotExist) && !isDirError(err) {
+		return objectHash{}, false, fmt.Errorf("failed to read reference %s: %w", name, err)
+	}
+
+	if !strings.HasPrefix(name, "refs/") {
+		return objectHash{}, false, nil
+	}
+	return readPackedRef(filepath.Join(dir, "packed-refs"), name)
+}
+
+// isDirError is a function that tells whether an error comes from reading a directory as a file, as with a reference named like a directory of references.
+func isDirError(err error) bool {
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) {
+		return false
+	}
+	info, statErr := os.Stat(pathErr.Path)
+	return statErr == nil && info.IsDir()
+}
+
+// readPackedRef is a function that looks up a reference in a packed-refs file, made of "<object> <name>" lines.
+func readPackedRef(path, name string) (objectHash, bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return objectHash{}, false, nil
+	}
+	if err != nil {
+		return objectHash{}, false, fmt.Errorf("failed to read packed references: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Comments and the peeled objects of annotated tags ("^<object>") are skipped
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		object, refName, ok := strings.Cut(line, " ")
+		if !ok || refName != name {
+			continue
+		}
+		hash, ok := parseObjectHash(object)
+		if !ok {
+			return objectHash{}, false, fmt.Errorf("invalid packed reference %s", name)
+		}
+		return hash, true, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return objectHash{}, false, fmt.Errorf("failed to read packed references: %w", err)
+	}
+	return objectHash{}, false, nil
+}
+
+// peelToCommit is a function that follows annotated tags until a commit.
+func (repo *gitRepo) peelToCommit(hash objectHash) (objectHash, error) {
+	for depth := 0; ; depth++ {
+		kind, data, err := repo.db.readObject(hash)
+		if err != nil {
+			return objectHash{}, err
+		}
+		switch {
+		case kind == objectCommit:
+			return hash, nil
+		case kind == objectTag && depth < maxSymbolicRefs:
+			// The tagged object is on the "object <name>" header line
+			target, ok := objectHeader(data, "object")
+			if !ok {
+				return objectHash{}, fmt.Errorf("invalid tag %s", hash)
+			}
+			tagged, ok := parseObjectHash(target)
+			if !ok {
+				return objectHash{}, fmt.Errorf("invalid tag %s", hash)
+			}
+			hash = tagged
+		default:
+			return objectHash{}, fmt.Errorf("object %s is a %s, not a commit", hash, kind)
+		}
+	}
+}
+
+// objectHeader is a function that returns the value of the first header line of a commit or tag object with the given key.
+func objectHeader(data []byte, key string) (string, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, key+" "); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// readCommit is a function that reads and parses a commit.
+func (repo *gitRepo) readCommit(hash objectHash) (*commitInfo, error) {
+	data, err := repo.db.readTypedObject(hash, objectCommit)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommit(hash, data)
+}
+
+// parseCommit is a function that parses the headers and the subject of a commit object.
+func parseCommit(hash objectHash, data []byte) (*commitInfo, error) {
+	commit := &commitInfo{Hash: hash}
+	headers, message, _ := bytes.Cut(data, []byte("\n\n"))
+	for _, line := range strings.Split(string(headers), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			tree, ok := parseObjectHash(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid tree in commit %s", hash)
+			}
+			commit.Tree = tree
+		case "parent":
+			parent, ok := parseObjectHash(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid parent in commit %s", hash)
+			}
+			commit.Parents = append(commit.Parents, parent)
+		case "author":
+			commit.Author, _ = parseSignature(value)
+		case "committer":
+			_, commit.Time = parseSignature(value)
+		}
+	}
+	subject, _, _ := strings.Cut(string(message), "\n")
+	commit.Subject = strings.TrimSpace(subject)
+	return commit, nil
+}
+
+// parseSignature is a function that parses the "<name> <<email>> <seconds> <zone>" value of an author or committer line.
+// It returns the name and email, and the time in the time zone of the signature.
+func parseSignature(value string) (string, time.Time) {
+	end := strings.LastIndexByte(value, '>')
+	if end < 0 {
+		return value, time.Time{}
+	}
+	identity := value[:end+1]
+	fields := strings.Fields(value[end+1:])
+	if len(fields) == 0 {
+		return identity, time.Time{}
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return identity, time.Time{}
+	}
+	t := time.Unix(seconds, 0)
+	if len(fields) > 1 {
+		if zone, err := time.Parse("-0700", fields[1]); err == nil {
+			t = t.In(zone.Location())
+		}
+	}
+	return identity, t
+}
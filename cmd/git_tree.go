@@ -0,0 +1,527 @@
+// cmd/git_tree.go
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// treeEntry is an entry of a git tree object: a file, a directory, a symbolic link or a submodule.
+type treeEntry struct {
+	Name string
+	Mode uint32
+	Hash objectHash
+}
+
+// parseTree is a function that parses the entries of a tree object, each made of "<octal mode> <name>\0" and a binary object name.
+func parseTree(data []byte) ([]treeEntry, error) {
+	var entries []treeEntry
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		end := bytes.IndexByte(data, 0)
+		if space < 0 || end < space || end+1+len(objectHash{}) > len(data) {
+			return nil, fmt.Errorf("invalid tree object")
+		}
+		mode, err := strconv.ParseUint(string(data[:space]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree object: %w", err)
+		}
+		entry := treeEntry{Name: string(data[space+1 : end]), Mode: uint32(mode)}
+		copy(entry.Hash[:], data[end+1:])
+		entries = append(entries, entry)
+		data = data[end+1+len(objectHash{}):]
+	}
+	return entries, nil
+}
+
+// changedBlobs is a function that lists the regular files that differ between two trees of an object database, with their old and new object,
+// a zero hash standing for a missing tree or file. It takes the path of the trees, "" for root trees, and a function telling which files
+// and directories to visit, so that unwanted subtrees are not read. Identical subtrees are skipped without being read.
+func changedBlobs(db *objectDB, oldTree, newTree objectHash, dir string, keep func(name string, isDir bool) bool, changes map[string][2]objectHash) error {
+	// readEntries returns the entries of a tree by name, none for a zero hash
+	readEntries := func(hash objectHash) (map[string]treeEntry, error) {
+		entries := make(map[string]treeEntry)
+		if hash == (objectHash{}) {
+			return entries, nil
+		}
+		data, err := db.readTypedObject(hash, objectTree)
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseTree(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree %s: %w", hash, err)
+		}
+		for _, entry := range list {
+			entries[entry.Name] = entry
+		}
+		return entries, nil
+	}
+	oldEntries, err := readEntries(oldTree)
+	if err != nil {
+		return err
+	}
+	newEntries, err := readEntries(newTree)
+	if err != nil {
+		return err
+	}
+
+	// Visit the names of both trees
+	names := make(map[string]bool)
+	for name := range oldEntries {
+		names[name] = true
+	}
+	for name := range newEntries {
+		names[name] = true
+	}
+	for name := range names {
+		oldEntry, newEntry := oldEntries[name], newEntries[name]
+		if oldEntry == newEntry {
+			continue
+		}
+		name = path.Join(dir, name)
+
+		// hashOf returns the object of an entry if it has the given type, a zero hash otherwise
+		hashOf := func(entry treeEntry, mode uint32) objectHash {
+			if entry.Mode&gitModeTypeMask == mode {
+				return entry.Hash
+			}
+			return objectHash{}
+		}
+		oldDir, newDir := hashOf(oldEntry, gitModeDir), hashOf(newEntry, gitModeDir)
+		if oldDir != newDir && keep(name, true) {
+			if err := changedBlobs(db, oldDir, newDir, name, keep, changes); err != nil {
+				return err
+			}
+		}
+		oldFile, newFile := hashOf(oldEntry, gitModeFile), hashOf(newEntry, gitModeFile)
+		if oldFile != newFile && keep(name, false) {
+			changes[name] = [2]objectHash{oldFile, newFile}
+		}
+	}
+	return nil
+}
+
+// treeFS is a read-only file system of the files of a directory at a git revision, read from the object database.
+// It implements fs.ReadDirFS, fs.ReadFileFS and fs.StatFS. Symbolic links are left out, and submodules are too unless
+// they are recursed into, in which case their files are read from their repository in the modules directory of the superproject.
+type treeFS struct {
+	repo *gitRepo
+
+	// commit is the commit the files are read from.
+	commit *commitInfo
+
+	// submodules is the --submodules mode.
+	submodules string
+
+	// dirs caches the directories read so far, by path.
+	dirs map[string]*treeDir
+
+	// dbs lists the object databases opened for submodules, closed with the file system.
+	dbs []*objectDB
+
+	// ownsRepo tells whether the repository was opened for the file system, and is closed with it.
+	ownsRepo bool
+}
+
+// treeDir is a directory of a treeFS.
+type treeDir struct {
+	// entries lists the files and directories, sorted by name. Submodules recursed into are directories too.
+	entries []treeEntry
+
+	// db is the object database and gitDir the git directory of the repository the directory belongs to,
+	// commit the commit of that repository the directory is read from, and repoPath the path of the directory in that repository.
+	db       *objectDB
+	gitDir   string
+	commit   objectHash
+	repoPath string
+}
+
+// openRevisionFS is a function that opens the files of a directory of a git working tree as they are at a revision.
+// The file system must be closed with its Close method.
+func openRevisionFS(dir, rev, submodules string) (*treeFS, error) {
+	if err := checkSubmoduleMode(submodules); err != nil {
+		return nil, err
+	}
+	repo, err := openGitRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := openTreeFS(repo, dir, rev, submodules)
+	if err != nil {
+		repo.close()
+		return nil, err
+	}
+	t.ownsRepo = true
+	return t, nil
+}
+
+// openTreeFS is a function that opens the files of a directory of the working tree of a repository as they are at a revision.
+// It returns an error wrapping fs.ErrNotExist if the directory does not exist at the revision.
+// The file system must be closed with its Close method, which leaves the repository open.
+func openTreeFS(repo *gitRepo, dir, rev, submodules string) (*treeFS, error) {
+	t, err := newTreeFS(repo, rev, submodules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start at the directory
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+	prefix, err := filepath.Rel(repo.worktree, absDir)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("failed to get path of %s in the repository: %w", dir, err)
+	}
+	if prefix != "." {
+		if err := t.chroot(filepath.ToSlash(prefix)); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// newTreeFS is a function that creates the file system of the root tree of a revision of a repository.
+func newTreeFS(repo *gitRepo, rev, submodules string) (*treeFS, error) {
+	hash, err := repo.resolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.readCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &treeFS{repo: repo, commit: commit, submodules: submodules, dirs: make(map[string]*treeDir)}
+	root, err := t.readTree(".", repo.db, commit.Tree, repo.gitDir, commit.Hash, ".")
+	if err != nil {
+		return nil, err
+	}
+	t.dirs["."] = root
+	return t, nil
+}
+
+// chroot makes a subdirectory the root of the file system.
+func (t *treeFS) chroot(dir string) error {
+	root, err := t.dir(dir)
+	if err != nil {
+		return fmt.Errorf("directory %s does not exist at revision %s: %w", dir, t.commit.Hash, fs.ErrNotExist)
+	}
+	// Keep the directories read below the new root
+	dirs := map[string]*treeDir{".": root}
+	for name, cached := range t.dirs {
+		if rest, ok := strings.CutPrefix(name, dir+"/"); ok {
+			dirs[rest] = cached
+		}
+	}
+	t.dirs = dirs
+	return nil
+}
+
+// Close closes the object databases of the submodules, and of the repository if it was opened for the file system.
+func (t *treeFS) Close() error {
+	for _, db := range t.dbs {
+		db.close()
+	}
+	if t.ownsRepo {
+		t.repo.close()
+	}
+	return nil
+}
+
+// readTree reads the tree object of a directory, keeping the regular files, the directories and the submodules recursed into.
+// The submodules are read right away, to leave out the ones whose repository is not available.
+func (t *treeFS) readTree(name string, db *objectDB, hash objectHash, gitDir string, commit objectHash, repoPath string) (*treeDir, error) {
+	data, err := db.readTypedObject(hash, objectTree)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree %s: %w", hash, err)
+	}
+
+	dir := &treeDir{db: db, gitDir: gitDir, commit: commit, repoPath: repoPath}
+	for _, entry := range entries {
+		switch entry.Mode & gitModeTypeMask {
+		case gitModeFile, gitModeDir:
+			dir.entries = append(dir.entries, entry)
+		case gitModeGitlink:
+			if t.submodules == submodulesRecurse {
+				if sub, err := t.submodule(path.Join(name, entry.Name), dir, entry); err == nil {
+					t.dirs[path.Join(name, entry.Name)] = sub
+					dir.entries = append(dir.entries, entry)
+				}
+			}
+		}
+	}
+
+	// Trees sort directories as if their names ended with a slash, directory listings sort by name
+	sort.Slice(dir.entries, func(i, j int) bool {
+		return dir.entries[i].Name < dir.entries[j].Name
+	})
+	return dir, nil
+}
+
+// submodule reads the root directory of a submodule, from its repository in the modules directory of the git directory of the superproject.
+// It returns an error if the repository or the commit of the submodule is not available.
+func (t *treeFS) submodule(name string, parent *treeDir, entry treeEntry) (*treeDir, error) {
+	gitDir := filepath.Join(commonGitDir(parent.gitDir), "modules", filepath.FromSlash(path.Join(parent.repoPath, entry.Name)))
+	if _, err := os.Stat(gitDir); err != nil {
+		return nil, err
+	}
+	db, err := openObjectDB(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	t.dbs = append(t.dbs, db)
+
+	data, err := db.readTypedObject(entry.Hash, objectCommit)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := parseCommit(entry.Hash, data)
+	if err != nil {
+		return nil, err
+	}
+	return t.readTree(name, db, commit.Tree, gitDir, commit.Hash, ".")
+}
+
+// dir returns a directory of the file system, reading the trees leading to it as needed.
+func (t *treeFS) dir(name string) (*treeDir, error) {
+	if dir, ok := t.dirs[name]; ok {
+		return dir, nil
+	}
+	parent, entry, err := t.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	// Submodules are read with the directory holding them, so only the directories of the tree are left
+	if entry.Mode&gitModeTypeMask != gitModeDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	dir, err := t.readTree(name, parent.db, entry.Hash, parent.gitDir, parent.commit, path.Join(parent.repoPath, entry.Name))
+	if err != nil {
+		return nil, err
+	}
+	t.dirs[name] = dir
+	return dir, nil
+}
+
+// isTreeDir tells whether an entry of a directory is a directory: a tree, or a submodule recursed into.
+func isTreeDir(entry treeEntry) bool {
+	mode := entry.Mode & gitModeTypeMask
+	return mode == gitModeDir || mode == gitModeGitlink
+}
+
+// lookup returns the directory holding an entry and the entry, or an fs.ErrNotExist error.
+func (t *treeFS) lookup(name string) (*treeDir, treeEntry, error) {
+	notExist := &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	if !fs.ValidPath(name) {
+		return nil, treeEntry{}, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil, treeEntry{}, notExist
+	}
+
+	parent, err := t.dir(path.Dir(name))
+	if err != nil {
+		return nil, treeEntry{}, notExist
+	}
+	base := path.Base(name)
+	i := sort.Search(len(parent.entries), func(i int) bool {
+		return parent.entries[i].Name >= base
+	})
+	if i == len(parent.entries) || parent.entries[i].Name != base {
+		return nil, treeEntry{}, notExist
+	}
+	return parent, parent.entries[i], nil
+}
+
+// Open opens a file or a directory.
+func (t *treeFS) Open(name string) (fs.File, error) {
+	info, err := t.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := t.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &treeDirFile{info: info, entries: entries}, nil
+	}
+	data, err := t.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &treeFile{info: info, Reader: bytes.NewReader(data)}, nil
+}
+
+// Stat returns the file info of a file or a directory.
+func (t *treeFS) Stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return &treeFileInfo{name: ".", mode: fs.ModeDir | 0o755, modTime: t.commit.Time}, nil
+	}
+	parent, entry, err := t.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.entryInfo(parent, entry)
+}
+
+// ReadDir returns the entries of a directory, sorted by name.
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	dir, err := t.dir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, len(dir.entries))
+	for i, entry := range dir.entries {
+		entries[i] = &treeDirEntry{fsys: t, parent: dir, entry: entry}
+	}
+	return entries, nil
+}
+
+// blobHash returns the object name of the content of a file, which identifies files with the same content.
+func (t *treeFS) blobHash(name string) (objectHash, error) {
+	_, entry, err := t.lookup(name)
+	if err != nil {
+		return objectHash{}, err
+	}
+	if entry.Mode&gitModeTypeMask != gitModeFile {
+		return objectHash{}, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return entry.Hash, nil
+}
+
+// fileSource returns where a file of the file system comes from: the git directory of its repository, which is the one of a submodule
+// for the files of submodules, the commit of that repository and the path of the file in that commit.
+func (t *treeFS) fileSource(name string) (string, objectHash, string, error) {
+	parent, entry, err := t.lookup(name)
+	if err != nil {
+		return "", objectHash{}, "", err
+	}
+	return parent.gitDir, parent.commit, path.Join(parent.repoPath, entry.Name), nil
+}
+
+// ReadFile returns the content of a file.
+func (t *treeFS) ReadFile(name string) ([]byte, error) {
+	parent, entry, err := t.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Mode&gitModeTypeMask != gitModeFile {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	data, err := parent.db.readTypedObject(entry.Hash, objectBlob)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// entryInfo returns the file info of an entry of a directory. The size of files is read from the object database.
+func (t *treeFS) entryInfo(parent *treeDir, entry treeEntry) (fs.FileInfo, error) {
+	info := &treeFileInfo{name: entry.Name, modTime: t.commit.Time}
+	if isTreeDir(entry) {
+		info.mode = fs.ModeDir | 0o755
+		return info, nil
+	}
+	size, err := parent.db.objectSize(entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	info.size = size
+	info.mode = fs.FileMode(entry.Mode & 0o777)
+	return info, nil
+}
+
+// treeFileInfo is the file info of an entry of a treeFS. Files have the time of the commit as modification time.
+type treeFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *treeFileInfo) Name() string       { return i.name }
+func (i *treeFileInfo) Size() int64        { return i.size }
+func (i *treeFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *treeFileInfo) ModTime() time.Time { return i.modTime }
+func (i *treeFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *treeFileInfo) Sys() any           { return nil }
+
+// treeDirEntry is an entry of a directory of a treeFS. Its file info is only read when requested.
+type treeDirEntry struct {
+	fsys   *treeFS
+	parent *treeDir
+	entry  treeEntry
+}
+
+func (e *treeDirEntry) Name() string { return e.entry.Name }
+func (e *treeDirEntry) IsDir() bool  { return isTreeDir(e.entry) }
+func (e *treeDirEntry) Type() fs.FileMode {
+	if e.IsDir() {
+		return fs.ModeDir
+	}
+	return 0
+}
+func (e *treeDirEntry) Info() (fs.FileInfo, error) { return e.fsys.entryInfo(e.parent, e.entry) }
+
+// treeFile is an open file of a treeFS.
+type treeFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+// treeDirFile is an open directory of a treeFS.
+type treeDirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDirFile) Close() error               { return nil }
+func (d *treeDirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir returns the next n entries of the directory, or all the remaining ones if n <= 0, as fs.ReadDirFile requires.
+func (d *treeDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,361 @@
+// cmd/history.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyFormat, historyOutput, historySince, historyUntil and historyEvery hold the values of the flags of the history command.
+var (
+	historyFormat string
+	historyOutput string
+	historySince  string
+	historyUntil  string
+	historyEvery  string
+)
+
+// Periods accepted by the --every flag of the history command.
+const (
+	periodDay   = "day"
+	periodWeek  = "week"
+	periodMonth = "month"
+	periodYear  = "year"
+)
+
+// historyTimeLayouts lists the layouts accepted by the --since and --until flags, the date-only one first.
+var historyTimeLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339}
+
+// historySampling selects the commits of the history to count: every N-th commit, or the last commit of every period.
+type historySampling struct {
+	commits int
+	period  string
+}
+
+// historySample is the report of a sampled commit.
+type historySample struct {
+	Commit *commitInfo
+	Report *report
+}
+
+// historyWriter writes the samples of the history in one output format.
+type historyWriter func(w io.Writer, samples []historySample) error
+
+// historyFormats maps the names accepted by the --format flag of the history command to their writers.
+var historyFormats = map[string]historyWriter{
+	"csv":  writeCSVHistory,
+	"tsv":  writeTSVHistory,
+	"json": writeJSONHistory,
+}
+
+// blobKey identifies the counts of a file content in a language, which are the same in every commit holding the same content.
+type blobKey struct {
+	hash     objectHash
+	language string
+	category string
+	comment  string
+}
+
+// historyCmd counts the lines of code at the commits of the history of the repository.
+var historyCmd = &cobra.Command{
+	Use:   "history [REV]",
+	Short: "Count the lines of code over the commit history",
+	Long: `Count the lines of code at the commits of the history of the git repository of the current directory,
+to chart the growth of the codebase.
+
+The first-parent history of REV (HEAD by default) is walked from the object database, without checking
+out any commit. --since and --until limit the commits by commit date, and --every samples them: every
+N-th commit, or the last commit of every day, week, month or year. Newest commits are kept first, so the
+latest state is always part of the series. Files are selected and counted exactly like the main command
+does, and the counts of every file content are reused by the later commits holding the same content.
+
+The csv and tsv formats write one record per sampled commit and language, the json format one sample
+per commit with its totals and its languages.`,
+	Example: `  locc history --every month --output history.csv
+  locc history --since 2023-01-01 --every week --format json v2.0.0`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		run, err := loadGlobalRunConfig(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rev := "HEAD"
+		if len(args) > 0 {
+			rev = args[0]
+		}
+		err = runHistory(run, rev)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runHistory is a function that counts the lines of code at the sampled commits of the history of a revision and writes the series.
+// It takes the configuration of the run, layered with the local configuration of every sampled commit, and the revision to start from as input.
+func runHistory(run *runConfig, rev string) error {
+	writer, ok := historyFormats[historyFormat]
+	if !ok {
+		var names []string
+		for name := range historyFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown output format %q, expected one of %s", historyFormat, strings.Join(names, ", "))
+	}
+	if revision != "" {
+		return fmt.Errorf("--rev cannot be used with history, give the revision as argument")
+	}
+	if err := checkSubmoduleMode(submoduleMode); err != nil {
+		return err
+	}
+	sampling, err := parseSampling(historyEvery)
+	if err != nil {
+		return err
+	}
+	since, err := parseHistoryTime(historySince, false)
+	if err != nil {
+		return err
+	}
+	until, err := parseHistoryTime(historyUntil, true)
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	repo, err := openGitRepo(cwd)
+	if err != nil {
+		return err
+	}
+	defer repo.close()
+
+	start, err := repo.resolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	commits, err := walkFirstParents(repo, start, since, until)
+	if err != nil {
+		return err
+	}
+	commits = sampling.sample(commits)
+
+	// Count the sampled commits, from the oldest
+	cache := make(map[blobKey]fileResult)
+	samples := make([]historySample, 0, len(commits))
+	for i := len(commits) - 1; i >= 0; i-- {
+		r, err := countCommit(repo, cwd, commits[i], run, cache)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, historySample{Commit: commits[i], Report: r})
+	}
+
+	return writeOutput(historyOutput, func(w io.Writer) error {
+		return writer(w, samples)
+	})
+}
+
+// walkFirstParents is a function that lists the commits of the first-parent history of a commit, newest first.
+// Commits older than since stop the walk, and commits newer than until are skipped, a zero time meaning no limit.
+// The walk also stops at the boundary of a shallow clone, whose parents are missing.
+func walkFirstParents(repo *gitRepo, start objectHash, since, until time.Time) ([]*commitInfo, error) {
+	var commits []*commitInfo
+	for hash := start; ; {
+		commit, err := repo.readCommit(hash)
+		if errors.Is(err, errObjectNotFound) && len(commits) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !since.IsZero() && commit.Time.Before(since) {
+			break
+		}
+		if until.IsZero() || !commit.Time.After(until) {
+			commits = append(commits, commit)
+		}
+		if len(commit.Parents) == 0 {
+			break
+		}
+		hash = commit.Parents[0]
+	}
+	return commits, nil
+}
+
+// parseSampling is a function that parses the value of the --every flag: a number of commits, such as "10" or "10 commits",
+// or day, week, month or year.
+// An empty value samples every commit.
+func parseSampling(every string) (historySampling, error) {
+	switch every {
+	case "":
+		return historySampling{commits: 1}, nil
+	case periodDay, periodWeek, periodMonth, periodYear:
+		return historySampling{period: every}, nil
+	}
+	// The number of commits may be followed by the unit, as in "10 commits"
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(every, "commits"), "commit")))
+	if err != nil || n <= 0 {
+		return historySampling{}, fmt.Errorf("invalid sampling %q, expected a number of commits, %s, %s, %s or %s", every, periodDay, periodWeek, periodMonth, periodYear)
+	}
+	return historySampling{commits: n}, nil
+}
+
+// sample selects the commits of a history, given newest first: every N-th commit starting from the newest,
+// or the newest commit of every period, in the time zone of the commits.
+func (s historySampling) sample(commits []*commitInfo) []*commitInfo {
+	var sampled []*commitInfo
+	seen := make(map[string]bool)
+	for i, commit := range commits {
+		if s.period == "" {
+			if i%s.commits == 0 {
+				sampled = append(sampled, commit)
+			}
+			continue
+		}
+		if key := periodKey(commit.Time, s.period); !seen[key] {
+			seen[key] = true
+			sampled = append(sampled, commit)
+		}
+	}
+	return sampled
+}
+
+// periodKey is a function that returns the name of the period holding a time, such as "2024-W07" for a week.
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case periodDay:
+		return t.Format("2006-01-02")
+	case periodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case periodMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006")
+	}
+}
+
+// parseHistoryTime is a function that parses the value of the --since or --until flag, in the local time zone unless a zone is given.
+// A date without a time stands for the start of the day, or for its end when end is true, so that both limits are inclusive.
+// A number of days, weeks, months or years, such as "90d" or "1y", stands for that long before now.
+// An empty value returns the zero time.
+func parseHistoryTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+		now := time.Now()
+		switch value[len(value)-1] {
+		case 'd':
+			return now.AddDate(0, 0, -n), nil
+		case 'w':
+			return now.AddDate(0, 0, -7*n), nil
+		case 'm':
+			return now.AddDate(0, -n, 0), nil
+		case 'y':
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	for i, layout := range historyTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if i == 0 && end {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, YYYY-MM-DD HH:MM:SS, RFC 3339 or a duration such as 90d, 12w, 6m or 1y", value)
+}
+
+// countCommit is a function that counts the lines of the files of the current directory at a commit.
+// The counts of every file content are kept in the cache and reused for the same content in the same language.
+// The configuration file of the directory is read from the commit too, so that every commit is counted and hashed with
+// the configuration it had. A directory missing at the commit has no files.
+func countCommit(repo *gitRepo, dir string, commit *commitInfo, run *runConfig, cache map[blobKey]fileResult) (*report, error) {
+	tree, err := openTreeFS(repo, dir, commit.Hash.String(), submoduleMode)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var localConfig *Config
+	if err == nil {
+		defer tree.Close()
+		localConfig, err = loadTreeConfig(tree)
+	} else {
+		localConfig, err = loadTreeConfig(nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration of commit %s: %w", commit.Hash, err)
+	}
+	config, categories, err := run.layer(localConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration of commit %s: %w", commit.Hash, err)
+	}
+
+	var files []fileResult
+	if tree != nil {
+		filesToProcess, err := buildFileList(config, tree, categories)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build file list of commit %s: %w", commit.Hash, err)
+		}
+		for _, file := range filesToProcess {
+			// If the language is not supported, skip the file
+			if file.Language == "" {
+				continue
+			}
+
+			hash, err := tree.blobHash(file.Path)
+			if err != nil {
+				return nil, err
+			}
+			key := blobKey{hash: hash, language: file.Language, category: file.Category, comment: strings.Join(file.Comment, "\x00")}
+			result, ok := cache[key]
+			if !ok {
+				content, err := tree.ReadFile(file.Path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file %s at commit %s: %w", file.Path, commit.Hash, err)
+				}
+				result = fileResult{
+					Language:  file.Language,
+					Category:  file.Category,
+					lineStats: countLines(content, file.Comment),
+					Bytes:     int64(len(content)),
+					Generated: isGenerated(content),
+				}
+				cache[key] = result
+			}
+			result.Path = file.Path
+			files = append(files, result)
+		}
+	}
+
+	r := newReport(files)
+	r.ConfigHash = configHash(config, categories)
+	r.Revision = commit.Hash.String()
+	return r, nil
+}
+
+// Registers the history command and its flags.
+func init() {
+	historyCmd.Flags().StringVarP(&historyFormat, "format", "f", "csv", "Output format: csv, tsv or json")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "", "Output file name (optional)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only count the commits made on or after this date")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Only count the commits made on or before this date")
+	historyCmd.Flags().StringVar(&historyEvery, "every", "", "Count every N-th commit, or the last commit of every day, week, month or year")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,355 @@
+// cmd/hotspots.go
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// hotspotsFormat, hotspotsOutput, hotspotsSince, hotspotsScore and hotspotsTop hold the values of the flags of the hotspots command.
+var (
+	hotspotsFormat string
+	hotspotsOutput string
+	hotspotsSince  string
+	hotspotsScore  string
+	hotspotsTop    int
+)
+
+// hotspotMetrics lists the metrics a hotspot score can be made of.
+var hotspotMetrics = []string{"code", "comment", "blank", "commits", "added", "removed", "churn"}
+
+// hotspot holds the size and the changes of a file.
+type hotspot struct {
+	// Path is the path of the file relative to the current directory, with forward slashes.
+	Path string
+
+	// Language and Category are the language detected for the file and the category it belongs to.
+	Language string
+	Category string
+
+	// lineStats holds the current line counts of the file.
+	lineStats
+
+	// Commits is the number of commits that changed the file in the window, Added and Removed the lines they added and removed.
+	Commits int
+	Added   int
+	Removed int
+
+	// Score is the product of the metrics of the score.
+	Score int64
+}
+
+// metric returns the value of a metric of the file, see hotspotMetrics. churn is the number of lines added and removed.
+func (h hotspot) metric(name string) int {
+	switch name {
+	case "code":
+		return h.Code
+	case "comment":
+		return h.Comment
+	case "blank":
+		return h.Blank
+	case "commits":
+		return h.Commits
+	case "added":
+		return h.Added
+	case "removed":
+		return h.Removed
+	default:
+		return h.Added + h.Removed
+	}
+}
+
+// hotspotReport holds the files ranked by score.
+type hotspotReport struct {
+	// Since is the start of the window, and Revision the commit the history was walked from.
+	Since    time.Time
+	Revision string
+
+	// Score is the score the files are ranked by, such as "code*commits".
+	Score string
+
+	// Files holds the files, the highest score first.
+	Files []hotspot
+
+	// ConfigHash identifies the effective configuration the files were counted with.
+	ConfigHash string
+}
+
+// hotspotWriter writes a hotspot report in one output format.
+type hotspotWriter func(w io.Writer, r *hotspotReport) error
+
+// hotspotFormats maps the names accepted by the --format flag of the hotspots command to their writers.
+var hotspotFormats = map[string]hotspotWriter{
+	"text": writeTextHotspots,
+	"json": writeJSONHotspots,
+	"csv":  writeCSVHotspots,
+	"tsv":  writeTSVHotspots,
+}
+
+// hotspotsCmd ranks the files by their size and how often they change.
+var hotspotsCmd = &cobra.Command{
+	Use:   "hotspots",
+	Short: "Rank the files that are both large and frequently changed",
+	Long: `Rank the files of the current directory that are both large and frequently changed, the usual suspects
+for refactoring.
+
+Files are selected and counted exactly like the main command does. Their changes over the window given
+with --since are read from the history of the local git repository, from HEAD or the --rev revision: the
+number of commits that changed them, merges excepted, and the lines those commits added and removed.
+Renamed files start over under their new name.
+
+Files are ranked by the product of the metrics given with --score, among code, comment, blank, commits,
+added, removed and churn (the lines added and removed). The default code*commits favors large files that
+keep changing, churn alone the files rewritten the most.`,
+	Example: `  locc hotspots
+  locc hotspots --since 6m --score code*churn --top 50 --output hotspots.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, categories, err := loadRunConfig(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = runHotspots(config, categories)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runHotspots is a function that ranks the files of the current directory by their score and writes the ranking.
+// It takes a configuration object and the set of enabled categories as input.
+func runHotspots(config *Config, categories categorySet) error {
+	writer, ok := hotspotFormats[hotspotsFormat]
+	if !ok {
+		var names []string
+		for name := range hotspotFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown output format %q, expected one of %s", hotspotsFormat, strings.Join(names, ", "))
+	}
+	score, err := parseHotspotScore(hotspotsScore)
+	if err != nil {
+		return err
+	}
+	if hotspotsTop < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+	since, err := parseHistoryTime(hotspotsSince, false)
+	if err != nil {
+		return err
+	}
+
+	// Count the lines of the files
+	r, err := collectReport(config, categories)
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	repo, err := openGitRepo(cwd)
+	if err != nil {
+		return err
+	}
+	defer repo.close()
+
+	// Read the changes of the files over the window
+	rev := "HEAD"
+	if revision != "" {
+		rev = revision
+	}
+	start, err := repo.resolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	prefix, err := filepath.Rel(repo.worktree, cwd)
+	if err != nil {
+		return fmt.Errorf("failed to get path of %s in the repository: %w", cwd, err)
+	}
+	files := make([]hotspot, len(r.Files))
+	byPath := make(map[string]*hotspot, len(r.Files))
+	for i, file := range r.Files {
+		files[i] = hotspot{Path: file.Path, Language: file.Language, Category: file.Category, lineStats: file.lineStats}
+		byPath[path.Join(filepath.ToSlash(prefix), file.Path)] = &files[i]
+	}
+	if err := readChurn(repo, start, since, byPath); err != nil {
+		return err
+	}
+
+	// Rank the files by score, then by lines of code and path
+	for i := range files {
+		files[i].Score = 1
+		for _, name := range score {
+			files[i].Score *= int64(files[i].metric(name))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Score != files[j].Score {
+			return files[i].Score > files[j].Score
+		}
+		return files[i].Code > files[j].Code
+	})
+	if hotspotsTop > 0 && len(files) > hotspotsTop {
+		files = files[:hotspotsTop]
+	}
+
+	ranking := &hotspotReport{
+		Since:      since,
+		Revision:   start.String(),
+		Score:      strings.Join(score, "*"),
+		Files:      files,
+		ConfigHash: configHash(config, categories),
+	}
+	return writeOutput(hotspotsOutput, func(w io.Writer) error {
+		return writer(w, ranking)
+	})
+}
+
+// parseHotspotScore is a function that parses a score, a product of metrics such as "code*commits", into the names of its metrics.
+func parseHotspotScore(score string) ([]string, error) {
+	var metrics []string
+	for _, name := range strings.Split(score, "*") {
+		name = strings.TrimSpace(name)
+		valid := false
+		for _, metric := range hotspotMetrics {
+			if metric == name {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown score metric %q, expected a product of %s", name, strings.Join(hotspotMetrics, ", "))
+		}
+		metrics = append(metrics, name)
+	}
+	return metrics, nil
+}
+
+// readChurn is a function that counts the commits that changed the given files since a time, and the lines they added and removed.
+// It takes the repository, the commit to walk the history from, the start of the window and the files by path in the repository.
+// Every commit reachable from the start and made after the start of the window is compared with its parent, merges excepted,
+// and the walk stops at the commits made before the window, like 'git log --since' does, and at the boundary of a shallow clone.
+func readChurn(repo *gitRepo, start objectHash, since time.Time, files map[string]*hotspot) error {
+	// Visit the directories holding the files only
+	dirs := make(map[string]bool)
+	for name := range files {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	keep := func(name string, isDir bool) bool {
+		if isDir {
+			return dirs[name]
+		}
+		return files[name] != nil
+	}
+
+	seen := map[objectHash]bool{start: true}
+	pending := []objectHash{start}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		commit, err := repo.readCommit(hash)
+		if errors.Is(err, errObjectNotFound) && hash != start {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !since.IsZero() && commit.Time.Before(since) {
+			continue
+		}
+		for _, parent := range commit.Parents {
+			if !seen[parent] {
+				seen[parent] = true
+				pending = append(pending, parent)
+			}
+		}
+		if len(commit.Parents) > 1 {
+			continue
+		}
+
+		// Compare the commit with its parent, a root commit adding all its files
+		var parentTree objectHash
+		if len(commit.Parents) == 1 {
+			parent, err := repo.readCommit(commit.Parents[0])
+			if errors.Is(err, errObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			parentTree = parent.Tree
+		}
+		changes := make(map[string][2]objectHash)
+		if err := changedBlobs(repo.db, parentTree, commit.Tree, "", keep, changes); err != nil {
+			return err
+		}
+		for name, blobs := range changes {
+			added, removed, err := changedLines(repo.db, blobs[0], blobs[1])
+			if err != nil {
+				return err
+			}
+			file := files[name]
+			file.Commits++
+			file.Added += added
+			file.Removed += removed
+		}
+	}
+	return nil
+}
+
+// changedLines is a function that returns the number of lines added and removed between two versions of a file,
+// a zero hash standing for a missing version.
+func changedLines(db *objectDB, from, to objectHash) (int, int, error) {
+	// readLines returns the lines of a version of the file
+	readLines := func(hash objectHash) ([]string, error) {
+		if hash == (objectHash{}) {
+			return nil, nil
+		}
+		data, err := db.readTypedObject(hash, objectBlob)
+		if err != nil {
+			return nil, err
+		}
+		return splitLines(data), nil
+	}
+	a, err := readLines(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := readLines(to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var added, removed int
+	for _, hunk := range diffLines(a, b) {
+		added += hunk.bEnd - hunk.bStart
+		removed += hunk.aEnd - hunk.aStart
+	}
+	return added, removed, nil
+}
+
+// Registers the hotspots command and its flags.
+func init() {
+	hotspotsCmd.Flags().StringVarP(&hotspotsFormat, "format", "f", "text", "Output format: text, json, csv or tsv")
+	hotspotsCmd.Flags().StringVarP(&hotspotsOutput, "output", "o", "", "Output file name (optional)")
+	hotspotsCmd.Flags().StringVar(&hotspotsSince, "since", "1y", "Start of the window of the history, a date or a duration such as 90d, 12w, 6m or 1y")
+	hotspotsCmd.Flags().StringVar(&hotspotsScore, "score", "code*commits", "Product of the metrics the files are ranked by: "+strings.Join(hotspotMetrics, ", "))
+	hotspotsCmd.Flags().IntVar(&hotspotsTop, "top", 20, "Number of files to list (0 for all)")
+	rootCmd.AddCommand(hotspotsCmd)
+}
@@ -0,0 +1,66 @@
+// cmd/import_cmd.go
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// importFrom and importOutput hold the values of the flags of the import command.
+var (
+	importFrom   string
+	importOutput string
+)
+
+// reportReader reads a report written by another line counter.
+type reportReader func(filename string) (*report, error)
+
+// importFormats maps the names accepted by the --from flag of the import command to their readers.
+var importFormats = map[string]reportReader{
+	"tokei": readTokeiReport,
+}
+
+// importCmd converts reports of other line counters into the native JSON report of locc,
+// so that historical snapshots can be compared with new runs.
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Convert a report of another line counter into a locc JSON report",
+	Long: `Convert a report of another line counter into a locc JSON report.
+
+Supported formats:
+  tokei   JSON written by 'tokei --output json'. Embedded code (e.g. CSS in HTML) is reported
+          under its own language in the 'embedded' category.
+
+Sizes, generated-code markers and the configuration hash are not part of these reports, so they are left empty.`,
+	Example: "  locc import --from tokei tokei.json --output loc.json",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reader, ok := importFormats[importFrom]
+		if !ok {
+			var names []string
+			for name := range importFormats {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown import format %q, expected one of %s", importFrom, strings.Join(names, ", "))
+		}
+
+		// Read the report and write it in the native JSON format
+		r, err := reader(args[0])
+		if err != nil {
+			return err
+		}
+		return writeReport("json", importOutput, r)
+	},
+}
+
+// Registers the import command and its flags.
+func init() {
+	importCmd.Flags().StringVar(&importFrom, "from", "", "Format of the report to import (tokei)")
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Output file name (optional)")
+	importCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,94 @@
+// cmd/language_names.go
+package cmd
+
+import "strings"
+
+// languageDisplayNames maps the language names of the default configuration to the names used by
+// other line counters such as cloc and tokei, for the output formats compatible with them.
+var languageDisplayNames = map[string]string{
+	"actionscript": "ActionScript",
+	"ada":          "Ada",
+	"assembly":     "Assembly",
+	"awk":          "awk",
+	"batch":        "DOS Batch",
+	"c":            "C",
+	"clojure":      "Clojure",
+	"cobol":        "COBOL",
+	"coffeescript": "CoffeeScript",
+	"coldfusion":   "ColdFusion",
+	"cpp":          "C++",
+	"crystal":      "Crystal",
+	"csharp":       "C#",
+	"css":          "CSS",
+	"csv":          "CSV",
+	"dart":         "Dart",
+	"delphi":       "Delphi",
+	"dockerfile":   "Dockerfile",
+	"elixir":       "Elixir",
+	"erlang":       "Erlang",
+	"fortran":      "Fortran",
+	"fsharp":       "F#",
+	"go":           "Go",
+	"groovy":       "Groovy",
+	"haml":         "Haml",
+	"handlebars":   "Handlebars",
+	"haskell":      "Haskell",
+	"html":         "HTML",
+	"ini":          "INI",
+	"java":         "Java",
+	"javascript":   "JavaScript",
+	"json":         "JSON",
+	"julia":        "Julia",
+	"kotlin":       "Kotlin",
+	"less":         "LESS",
+	"lisp":         "Lisp",
+	"lua":          "Lua",
+	"makefile":     "make",
+	"markdown":     "Markdown",
+	"matlab":       "MATLAB",
+	"nim":          "Nim",
+	"pascal":       "Pascal",
+	"perl":         "Perl",
+	"php":          "PHP",
+	"powershell":   "PowerShell",
+	"prolog":       "Prolog",
+	"python":       "Python",
+	"r":            "R",
+	"react":        "JSX",
+	"ruby":         "Ruby",
+	"rust":         "Rust",
+	"sass":         "Sass",
+	"scala":        "Scala",
+	"scheme":       "Scheme",
+	"scss":         "SCSS",
+	"shell":        "Bourne Shell",
+	"sql":          "SQL",
+	"svelte":       "Svelte",
+	"swift":        "Swift",
+	"tcl":          "Tcl/Tk",
+	"terraform":    "HCL",
+	"text":         "Text",
+	"toml":         "TOML",
+	"tsx":          "TSX",
+	"typescript":   "TypeScript",
+	"vb_net":       "Visual Basic .NET",
+	"verilog":      "Verilog-SystemVerilog",
+	"vhdl":         "VHDL",
+	"visual_basic": "Visual Basic",
+	"vue":          "Vuejs Component",
+	"xml":          "XML",
+	"yaml":         "YAML",
+	"zig":          "Zig",
+}
+
+// displayLanguage is a function that returns the display name of a language.
+// Languages without a known display name, such as custom languages, are capitalized instead.
+func displayLanguage(name string) string {
+	if display, ok := languageDisplayNames[name]; ok {
+		return display
+	}
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
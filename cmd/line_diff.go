@@ -0,0 +1,158 @@
+// cmd/line_diff.go
+package cmd
+
+// diffCostLimit is the number of edits after which the search for the middle snake gives up on finding a minimal diff,
+// so that comparing two large and unrelated versions of a file does not take quadratic time.
+const diffCostLimit = 1024
+
+// diffHunk is a run of changed lines: the lines [aStart, aEnd) of the old file are replaced by the lines [bStart, bEnd) of the new file.
+// Either range may be empty, for pure insertions and deletions.
+type diffHunk struct {
+	aStart, aEnd int
+	bStart, bEnd int
+}
+
+// diffLines is a function that computes the changes between two versions of a file, as a minimal list of hunks.
+// It uses the linear-space variant of the Myers algorithm, so the memory used stays proportional to the size of the files
+// even when they have little in common. Past diffCostLimit edits between two matching lines the hunks are close to minimal, not minimal.
+func diffLines(a, b []string) []diffHunk {
+	// Compare integers rather than strings, every distinct line getting its own number
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+
+	d := &lineDiffer{a: intern(a), b: intern(b)}
+	d.compare(0, len(a), 0, len(b))
+	return d.hunks
+}
+
+// lineDiffer holds the state of a comparison of two files.
+type lineDiffer struct {
+	a, b  []int
+	hunks []diffHunk
+}
+
+// compare finds the changes between a[aLo:aHi] and b[bLo:bHi], splitting the ranges around the middle snake of the edit path
+// until one of them is empty.
+func (d *lineDiffer) compare(aLo, aHi, bLo, bHi int) {
+	// Skip the common prefix and suffix
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	// What is left of one side is entirely replaced by what is left of the other
+	if aLo == aHi || bLo == bHi {
+		if aLo < aHi || bLo < bHi {
+			d.addHunk(diffHunk{aLo, aHi, bLo, bHi})
+		}
+		return
+	}
+
+	x0, y0, x1, y1 := d.middleSnake(aLo, aHi, bLo, bHi)
+	d.compare(aLo, x0, bLo, y0)
+	d.compare(x1, aHi, y1, bHi)
+}
+
+// addHunk appends a hunk, merging it with the previous one when they touch.
+func (d *lineDiffer) addHunk(h diffHunk) {
+	if n := len(d.hunks); n > 0 && d.hunks[n-1].aEnd == h.aStart && d.hunks[n-1].bEnd == h.bStart {
+		d.hunks[n-1].aEnd, d.hunks[n-1].bEnd = h.aEnd, h.bEnd
+		return
+	}
+	d.hunks = append(d.hunks, h)
+}
+
+// middleSnake finds the snake, a run of equal lines, in the middle of a shortest edit path between a[aLo:aHi] and b[bLo:bHi],
+// searching forward from the start and backward from the end at the same time.
+// It returns the start (x0, y0) and the end (x1, y1) of the snake.
+func (d *lineDiffer) middleSnake(aLo, aHi, bLo, bHi int) (int, int, int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+
+	// forward[k] is the furthest x reached on diagonal k = x - y from the start,
+	// backward[k] the furthest distance reached on diagonal k from the end
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for D := 0; D <= maxD; D++ {
+		// Too many edits: split at the point inside the ranges that the forward paths went furthest to instead,
+		// or in the middle of the ranges
+		if D > diffCostLimit {
+			bestX, bestY := n/2, m/2
+			found := false
+			for k := -D + 1; k <= D-1; k += 2 {
+				x := forward[offset+k]
+				y := x - k
+				if x < 0 || y < 0 || x > n || y > m || (x == 0 && y == 0) || (x == n && y == m) {
+					continue
+				}
+				if !found || x+y > bestX+bestY {
+					bestX, bestY, found = x, y, true
+				}
+			}
+			return aLo + bestX, bLo + bestY, aLo + bestX, bLo + bestY
+		}
+
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			// The paths meet when the forward path reaches the backward path on the same diagonal
+			if back := delta - k; odd && back >= -(D-1) && back <= D-1 && x+backward[offset+back] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if forw := delta - k; !odd && forw >= -D && forw <= D && x+forward[offset+forw] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+
+	// Unreachable for a shortest edit path, whose length is at most n + m
+	return aHi, bHi, aHi, bHi
+}
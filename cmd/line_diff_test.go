@@ -0,0 +1,144 @@
+// cmd/line_diff_test.go
+package cmd
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkHunks checks that hunks turn a into b: they must be in order, not empty, and the lines between them must be equal.
+// It returns the number of lines removed and added.
+func checkHunks(t *testing.T, a, b []string, hunks []diffHunk) (int, int) {
+	t.Helper()
+	var removed, added int
+	i, j := 0, 0
+	for _, h := range hunks {
+		if h.aStart < i || h.bStart < j || h.aEnd < h.aStart || h.bEnd < h.bStart || h.aEnd > len(a) || h.bEnd > len(b) {
+			t.Fatalf("invalid hunk %+v after a[%d] and b[%d]", h, i, j)
+		}
+		if h.aStart == h.aEnd && h.bStart == h.bEnd {
+			t.Fatalf("empty hunk %+v", h)
+		}
+		if h.aStart-i != h.bStart-j {
+			t.Fatalf("hunk %+v leaves %d and %d unchanged lines", h, h.aStart-i, h.bStart-j)
+		}
+		for ; i < h.aStart; i, j = i+1, j+1 {
+			if a[i] != b[j] {
+				t.Fatalf("unchanged lines a[%d] = %q and b[%d] = %q differ", i, a[i], j, b[j])
+			}
+		}
+		removed += h.aEnd - h.aStart
+		added += h.bEnd - h.bStart
+		i, j = h.aEnd, h.bEnd
+	}
+	if len(a)-i != len(b)-j {
+		t.Fatalf("the last hunk leaves %d and %d unchanged lines", len(a)-i, len(b)-j)
+	}
+	for ; i < len(a); i, j = i+1, j+1 {
+		if a[i] != b[j] {
+			t.Fatalf("unchanged lines a[%d] = %q and b[%d] = %q differ", i, a[i], j, b[j])
+		}
+	}
+	return removed, added
+}
+
+// lcsLength returns the length of the longest common subsequence of two files, with the quadratic dynamic programming algorithm.
+func lcsLength(a, b []string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				current[j+1] = previous[j] + 1
+			case previous[j+1] > current[j]:
+				current[j+1] = previous[j+1]
+			default:
+				current[j+1] = current[j]
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// randomLines returns n lines drawn from an alphabet of the given size.
+func randomLines(r *rand.Rand, n, alphabet int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = strconv.Itoa(r.Intn(alphabet))
+	}
+	return lines
+}
+
+func TestDiffLines(t *testing.T) {
+	lines := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	tests := []struct {
+		name  string
+		a, b  string
+		hunks []diffHunk
+	}{
+		{name: "both empty", a: "", b: "", hunks: nil},
+		{name: "old empty", a: "", b: "x y", hunks: []diffHunk{{0, 0, 0, 2}}},
+		{name: "new empty", a: "x y", b: "", hunks: []diffHunk{{0, 2, 0, 0}}},
+		{name: "identical", a: "x y z", b: "x y z", hunks: nil},
+		{name: "insert at the start", a: "b c", b: "a b c", hunks: []diffHunk{{0, 0, 0, 1}}},
+		{name: "insert in the middle", a: "a b c", b: "a x y b c", hunks: []diffHunk{{1, 1, 1, 3}}},
+		{name: "insert at the end", a: "a b", b: "a b c", hunks: []diffHunk{{2, 2, 2, 3}}},
+		{name: "delete at the start", a: "a b c", b: "b c", hunks: []diffHunk{{0, 1, 0, 0}}},
+		{name: "delete in the middle", a: "a x y b c", b: "a b c", hunks: []diffHunk{{1, 3, 1, 1}}},
+		{name: "delete at the end", a: "a b c", b: "a b", hunks: []diffHunk{{2, 3, 2, 2}}},
+		{name: "replace", a: "a b c", b: "a x c", hunks: []diffHunk{{1, 2, 1, 2}}},
+		{name: "nothing in common", a: "a b", b: "x y z", hunks: []diffHunk{{0, 2, 0, 3}}},
+		{name: "separate changes", a: "a b c d e", b: "a x c d y e", hunks: []diffHunk{{1, 2, 1, 2}, {4, 4, 4, 5}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := lines(test.a), lines(test.b)
+			hunks := diffLines(a, b)
+			if !reflect.DeepEqual(hunks, test.hunks) {
+				t.Errorf("diffLines(%q, %q) = %+v, want %+v", test.a, test.b, hunks, test.hunks)
+			}
+			checkHunks(t, a, b, hunks)
+		})
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a := randomLines(r, r.Intn(40), 1+r.Intn(6))
+		b := randomLines(r, r.Intn(40), 1+r.Intn(6))
+		removed, added := checkHunks(t, a, b, diffLines(a, b))
+		if common := lcsLength(a, b); removed != len(a)-common || added != len(b)-common {
+			t.Fatalf("diffLines(%q, %q) removes %d and adds %d lines, want %d and %d", a, b, removed, added, len(a)-common, len(b)-common)
+		}
+	}
+}
+
+func TestDiffLinesCostLimit(t *testing.T) {
+	// Long files with few lines in common need more than diffCostLimit edits, the hunks are still valid but may not be minimal
+	r := rand.New(rand.NewSource(1))
+	a := randomLines(r, 5000, 100)
+	b := randomLines(r, 5000, 100)
+	removed, added := checkHunks(t, a, b, diffLines(a, b))
+	if common := lcsLength(a, b); removed < len(a)-common || added < len(b)-common {
+		t.Fatalf("diffLines() removes %d and adds %d lines, fewer than the minimum %d and %d", removed, added, len(a)-common, len(b)-common)
+	}
+
+	// Files with nothing in common are entirely replaced
+	for i := range b {
+		b[i] = "new " + b[i]
+	}
+	if hunks := diffLines(a, b); !reflect.DeepEqual(hunks, []diffHunk{{0, len(a), 0, len(b)}}) {
+		t.Errorf("diffLines() of files with nothing in common = %+v, want a single hunk", hunks)
+	}
+}
@@ -0,0 +1,148 @@
+// cmd/output.go
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reportWriter writes a report in one output format.
+type reportWriter func(w io.Writer, r *report) error
+
+// reportFormats maps the names accepted by the --format flag to their writers.
+var reportFormats = map[string]reportWriter{
+	"text": writeTextReport,
+	"json": writeJSONReport,
+	"csv":  writeCSVReport,
+	"tsv":  writeTSVReport,
+	"html": writeHTMLReport,
+
+	// GitHub-flavoured Markdown, for READMEs and pull requests
+	"markdown": writeMarkdownReport,
+
+	// OpenMetrics exposition, for Prometheus and the node_exporter textfile collector
+	"openmetrics": writeOpenMetricsReport,
+
+	// cloc-compatible formats, for tools consuming cloc reports
+	"cloc-json": writeClocJSONReport,
+	"cloc-xml":  writeClocXMLReport,
+	"cloc-yaml": writeClocYAMLReport,
+
+	// tokei-compatible format, for tools consuming tokei reports
+	"tokei-json": writeTokeiJSONReport,
+}
+
+// formatAliases maps alternative names accepted by the --format flag to the names of the formats.
+var formatAliases = map[string]string{
+	"md": "markdown",
+}
+
+// formatNames is a function that returns the names of the supported output formats, sorted.
+func formatNames() []string {
+	var names []string
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveFormat is a function that returns the name of an output format given by name or by alias.
+// It returns false if the format is unknown.
+func resolveFormat(format string) (string, bool) {
+	if name, ok := formatAliases[format]; ok {
+		format = name
+	}
+	_, ok := reportFormats[format]
+	return format, ok
+}
+
+// unknownFormatError is a function that returns the error reported for an unknown output format.
+func unknownFormatError(format string) error {
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(formatNames(), ", "))
+}
+
+// stdoutPath is the path standing for the standard output in the values of the --output flag.
+const stdoutPath = "-"
+
+// outputTarget is a report to write: its format, and the file it is written to, or "" for the standard output.
+type outputTarget struct {
+	format string
+	path   string
+}
+
+// parseOutputs is a function that parses the values of the --output flag into the reports to write.
+// A value is either format=path, where format is the name or alias of an output format, or a path written in the default format.
+// The path "-" stands for the standard output. Without values, the report is written to the standard output in the default format.
+// It returns an error if a format is unknown or if two reports would be written to the same place.
+func parseOutputs(values []string, defaultFormat string) ([]outputTarget, error) {
+	if len(values) == 0 {
+		values = []string{stdoutPath}
+	}
+
+	var targets []outputTarget
+	seen := make(map[string]bool)
+	for _, value := range values {
+		target := outputTarget{format: defaultFormat, path: value}
+		// The value starts with a format only if the prefix is a known format, so paths containing '=' still work
+		if prefix, path, ok := strings.Cut(value, "="); ok {
+			if _, known := resolveFormat(prefix); known {
+				target = outputTarget{format: prefix, path: path}
+			}
+		}
+
+		format, ok := resolveFormat(target.format)
+		if !ok {
+			return nil, unknownFormatError(target.format)
+		}
+		target.format = format
+		if target.path == "" {
+			return nil, fmt.Errorf("missing output path in %q", value)
+		}
+		if seen[target.path] {
+			return nil, fmt.Errorf("output %s is given more than once", target.path)
+		}
+		seen[target.path] = true
+		if target.path == stdoutPath {
+			target.path = ""
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// writeReport is a function that writes a report in the given format to a file, or to the standard output if the path is empty.
+func writeReport(format, path string, r *report) error {
+	format, ok := resolveFormat(format)
+	if !ok {
+		return unknownFormatError(format)
+	}
+	writer := reportFormats[format]
+	return writeOutput(path, func(w io.Writer) error {
+		return writer(w, r)
+	})
+}
+
+// writeOutput is a function that writes an output to a file, or to the standard output if no file is given.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	// Write to the standard output if no file is given
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// cmd/overrides.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration.
+const envPrefix = "LOCC_"
+
+// envSeparator separates the entries of an environment variable holding several override values,
+// the same way repeating a flag does. Commas cannot be used since values like 'go=.go,.gox' contain them.
+const envSeparator = ";"
+
+// configOverride describes a configuration key that can be overridden from the environment and the command line.
+// Overrides are the highest-priority configuration layer: they are applied on top of the global and local files,
+// the selected profile and every per-directory configuration.
+type configOverride struct {
+	// name is the name of the flag, the environment variable is derived from it.
+	name string
+
+	// typeName is the name of the value shown in the help of the flag.
+	typeName string
+
+	// usage is the help text of the flag.
+	usage string
+
+	// apply applies one value of the override to a configuration with processed filters.
+	apply func(config *Config, value string) error
+
+	// values holds the values given with the flag, in order.
+	values []string
+}
+
+// envName returns the name of the environment variable of the override, e.g. LOCC_MAX_FILE_SIZE for --max-file-size.
+func (o *configOverride) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(o.name, "-", "_"))
+}
+
+// String implements pflag.Value.
+func (o *configOverride) String() string {
+	return strings.Join(o.values, ",")
+}
+
+// Set implements pflag.Value, every occurrence of the flag adds a value.
+func (o *configOverride) Set(value string) error {
+	o.values = append(o.values, value)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (o *configOverride) Type() string {
+	return o.typeName
+}
+
+// configOverrides lists every configuration key that can be overridden.
+// The languages are overridden first, so that the filters can name a language created by --lang-ext.
+// The profiles and the categories have no override: --profile selects a profile, --category and --no-category toggle categories,
+// and the languages of a category are overridden with --lang-ext and --lang-comment.
+var configOverrides = []*configOverride{
+	{
+		name:     "lang-ext",
+		typeName: "[category.]lang=exts",
+		usage:    "Set the comma-separated extensions of a language, e.g. go=.go,.gox or stores.json=.json (repeatable)",
+		apply: func(config *Config, value string) error {
+			return setLanguageField(config, value, func(langConfig *LanguageConfig, values []string) {
+				langConfig.Extensions = values
+			})
+		},
+	},
+	{
+		name:     "lang-comment",
+		typeName: "[category.]lang=syntax",
+		usage:    "Set the comment syntax of a language, e.g. lua=-- or html=<!--,--> (repeatable)",
+		apply: func(config *Config, value string) error {
+			return setLanguageField(config, value, func(langConfig *LanguageConfig, values []string) {
+				langConfig.Comment = values
+			})
+		},
+	},
+	{
+		name:     "exclude",
+		typeName: "[lang:]patterns",
+		usage:    "Exclude comma-separated files or folders, globally or for a language (repeatable)",
+		apply: func(config *Config, value string) error {
+			return addFilterRules(config, config.Excludes, value)
+		},
+	},
+	{
+		name:     "include",
+		typeName: "[lang:]patterns",
+		usage:    "Include comma-separated files, globally or for a language (repeatable)",
+		apply: func(config *Config, value string) error {
+			return addFilterRules(config, config.Includes, value)
+		},
+	},
+	{
+		name:     "max-file-size",
+		typeName: "bytes",
+		usage:    "Maximum file size to process in bytes",
+		apply: func(config *Config, value string) error {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return fmt.Errorf("invalid max file size %q", value)
+			}
+			config.MaxFileSize = size
+			return nil
+		},
+	},
+}
+
+// addFilterRules is a function that adds simple rules to a processed filter of a configuration.
+// The value is a comma-separated list of file or folder names, optionally prefixed by the language they apply to
+// and a colon. Rules without a language are added under the global 'locc' key.
+// The prefix ends at the first colon, so patterns holding a colon must be prefixed, e.g. locc:a:b.txt or go:a:b.go.
+// A prefix naming neither a language of the configuration nor 'locc' is rejected, rather than taken for a part of a pattern.
+func addFilterRules(config *Config, filter map[string]interface{}, value string) error {
+	key, patterns := "locc", value
+	if lang, rest, ok := strings.Cut(value, ":"); ok {
+		if lang != "locc" && !hasLanguage(config, lang) {
+			return fmt.Errorf("unknown language %q in %q, prefix patterns holding a colon with their language or locc, e.g. locc:%s", lang, value, value)
+		}
+		key, patterns = lang, rest
+	}
+
+	rules, ok := filter[key].(map[string][]string)
+	if !ok {
+		rules = make(map[string][]string)
+	}
+	// Copy the rules, they may be shared with the configuration of a parent directory
+	merged := make(map[string][]string, len(rules))
+	for filename, wordlist := range rules {
+		merged[filename] = wordlist
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		merged[pattern] = []string{}
+	}
+	filter[key] = merged
+	return nil
+}
+
+// hasLanguage is a function that checks whether a configuration defines a language in any category.
+func hasLanguage(config *Config, lang string) bool {
+	if _, ok := config.Languages[lang]; ok {
+		return true
+	}
+	if _, ok := config.Stores[lang]; ok {
+		return true
+	}
+	if _, ok := config.Documents[lang]; ok {
+		return true
+	}
+	for _, category := range config.Categories {
+		if _, ok := category.Languages[lang]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setLanguageField is a function that updates one field of a language configuration from a 'lang=values' override.
+// The language can be prefixed by a category and a dot to target stores, documents or a custom category,
+// otherwise it targets the 'languages' section. Languages that do not exist yet are created.
+func setLanguageField(config *Config, value string, set func(langConfig *LanguageConfig, values []string)) error {
+	name, list, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid language override %q, expected lang=values", value)
+	}
+
+	var values []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	// Find the map of languages the override applies to
+	category, lang := categoryLanguages, name
+	if before, after, ok := strings.Cut(name, "."); ok {
+		category, lang = before, after
+	}
+	var languages map[string]LanguageConfig
+	switch category {
+	case categoryLanguages:
+		languages = config.Languages
+	case categoryStores:
+		languages = config.Stores
+	case categoryDocuments:
+		languages = config.Documents
+	default:
+		custom, ok := config.Categories[category]
+		if !ok {
+			return fmt.Errorf("unknown category %q in language override %q", category, value)
+		}
+		// Copy the languages of the category, they may be shared with the configuration of a parent directory
+		languages = make(map[string]LanguageConfig, len(custom.Languages)+1)
+		for name, langConfig := range custom.Languages {
+			languages[name] = langConfig
+		}
+		custom.Languages = languages
+		config.Categories[category] = custom
+	}
+
+	langConfig := languages[lang]
+	set(&langConfig, values)
+	languages[lang] = langConfig
+	return nil
+}
+
+// applyConfigOverrides is a function that applies the overrides from the environment and the command line to a configuration.
+// It must be called after the filters of the configuration are processed.
+// Environment variables are applied first, so that flags take precedence over them.
+func applyConfigOverrides(config *Config) error {
+	for _, override := range configOverrides {
+		values := splitEnvList(os.Getenv(override.envName()), envSeparator)
+		values = append(values, override.values...)
+		for _, value := range values {
+			if err := override.apply(config, value); err != nil {
+				return fmt.Errorf("%s: %w", override.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// splitEnvList is a function that splits the value of an environment variable into its entries, ignoring empty ones.
+func splitEnvList(value, separator string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, separator) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// applyEnvSettings is a function that applies the environment variables of the settings that are not part of the configuration files.
+// LOCC_CONFIG and LOCC_PROFILE set the defaults of the --config and --profile flags.
+func applyEnvSettings(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if value, ok := os.LookupEnv(envPrefix + "CONFIG"); ok && !flags.Changed("config") {
+		configFile = value
+	}
+	if value, ok := os.LookupEnv(envPrefix + "PROFILE"); ok && !flags.Changed("profile") {
+		profileName = value
+	}
+}
+
+// categoryToggles is a function that returns the categories enabled and disabled from the environment and the command line.
+// LOCC_CATEGORY and LOCC_NO_CATEGORY hold comma-separated categories, and the --data and --docs flags given explicitly
+// act like --category stores and --category documents. A category toggled by both the environment and a flag follows the flag.
+func categoryToggles(cmd *cobra.Command) (enable, disable []string) {
+	enable = append(enable, enableCategory...)
+	disable = append(disable, disableCategory...)
+	flags := cmd.Flags()
+	if flags.Changed("data") {
+		if enableStores {
+			enable = append(enable, categoryStores)
+		} else {
+			disable = append(disable, categoryStores)
+		}
+	}
+	if flags.Changed("docs") {
+		if enableDocuments {
+			enable = append(enable, categoryDocuments)
+		} else {
+			disable = append(disable, categoryDocuments)
+		}
+	}
+
+	// Add the toggles of the environment that no flag contradicts
+	flagged := make(map[string]bool)
+	for _, name := range append(append([]string{}, enable...), disable...) {
+		flagged[name] = true
+	}
+	for _, name := range splitEnvList(os.Getenv(envPrefix+"CATEGORY"), ",") {
+		if !flagged[name] {
+			enable = append(enable, name)
+		}
+	}
+	for _, name := range splitEnvList(os.Getenv(envPrefix+"NO_CATEGORY"), ",") {
+		if !flagged[name] {
+			disable = append(disable, name)
+		}
+	}
+	return enable, disable
+}
+
+// registerOverrideFlags is a function that registers a flag for every configuration override.
+func registerOverrideFlags(flags *pflag.FlagSet) {
+	for _, override := range configOverrides {
+		flags.Var(override, override.name, fmt.Sprintf("%s (env %s)", override.usage, override.envName()))
+	}
+}
@@ -0,0 +1,53 @@
+// cmd/overrides_test.go
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddFilterRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		key     string
+		rules   []string
+		invalid bool
+	}{
+		{name: "global", value: "build/,dist/", key: "locc", rules: []string{"build/", "dist/"}},
+		{name: "language", value: "go:gen/, mock.go", key: "go", rules: []string{"gen/", "mock.go"}},
+		{name: "explicit global", value: "locc:a:b.txt", key: "locc", rules: []string{"a:b.txt"}},
+		{name: "language with a colon in the pattern", value: "go:gen:v2/", key: "go", rules: []string{"gen:v2/"}},
+		{name: "language of a custom category", value: "terraform:modules/", key: "terraform", rules: []string{"modules/"}},
+		{name: "unknown language", value: "gen:v2/", invalid: true},
+		{name: "windows path", value: `C:\build`, invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &Config{
+				Languages: map[string]LanguageConfig{"go": {Extensions: []string{".go"}}},
+				Categories: map[string]CategoryConfig{
+					"infrastructure": {Languages: map[string]LanguageConfig{"terraform": {Extensions: []string{".tf"}}}},
+				},
+			}
+			filter := map[string]interface{}{}
+			err := addFilterRules(config, filter, test.value)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("addFilterRules(%q) error = nil, want an error", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("addFilterRules(%q) error = %v", test.value, err)
+			}
+			want := map[string][]string{}
+			for _, rule := range test.rules {
+				want[rule] = []string{}
+			}
+			if !reflect.DeepEqual(filter, map[string]interface{}{test.key: want}) {
+				t.Errorf("addFilterRules(%q) = %v, want %s: %v", test.value, filter, test.key, want)
+			}
+		})
+	}
+}
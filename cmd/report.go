@@ -0,0 +1,375 @@
+// cmd/report.go
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileResult holds the counts of a single processed file.
+type fileResult struct {
+	// Path is the path of the file relative to the root directory, with forward slashes.
+	Path string
+
+	// Language and Category are the language detected for the file and the category it belongs to.
+	Language string
+	Category string
+
+	// lineStats holds the blank, comment and code line counts of the file.
+	lineStats
+
+	// Bytes is the size of the file.
+	Bytes int64
+
+	// Generated tells whether the file carries a marker of generated code, such as "Code generated ... DO NOT EDIT.".
+	Generated bool
+
+	// Owners holds the lines of the file attributed to each owner with --by author, --by email-domain and --by owner.
+	Owners map[string]lineStats
+}
+
+// languageSummary holds the aggregated counts of the files of one language, or of all files for the totals.
+type languageSummary struct {
+	// Language and Category identify the language. They are empty for the totals.
+	Language string
+	Category string
+
+	// Files is the number of files aggregated.
+	Files int
+
+	// lineStats holds the sum of the line counts of the files.
+	lineStats
+
+	// Bytes is the sum of the sizes of the files.
+	Bytes int64
+
+	// Generated is the number of generated files aggregated.
+	Generated int
+}
+
+// addFile adds the counts of a file to the summary.
+func (s *languageSummary) addFile(file fileResult) {
+	s.Files++
+	s.lineStats.add(file.lineStats)
+	s.Bytes += file.Bytes
+	if file.Generated {
+		s.Generated++
+	}
+}
+
+// report holds the results of a run, shared by every output format.
+type report struct {
+	// Files holds the results of every processed file, sorted by path.
+	Files []fileResult
+
+	// Languages holds the per-language aggregates, sorted by language name.
+	Languages []languageSummary
+
+	// Total holds the aggregate of all files.
+	Total languageSummary
+
+	// Owners holds the per-owner and per-language aggregates of the lines attributed to owners, sorted by owner and language name.
+	Owners []ownerSummary
+
+	// Categories lists the names of the enabled categories, built-in categories first.
+	Categories []string
+
+	// ConfigHash identifies the effective configuration the report was produced with.
+	ConfigHash string
+
+	// Revision is the commit the files were read from with --rev, empty for the working tree.
+	Revision string
+
+	// Elapsed is the time taken to build the file list and count the lines.
+	Elapsed time.Duration
+}
+
+// categoryTotals returns the aggregated counts of each category of the report.
+func (r *report) categoryTotals() map[string]languageSummary {
+	totals := make(map[string]languageSummary)
+	for _, file := range r.Files {
+		total := totals[file.Category]
+		total.Category = file.Category
+		total.addFile(file)
+		totals[file.Category] = total
+	}
+	return totals
+}
+
+// collectReport is a function that counts the lines of every file selected by the configuration in the current working directory.
+// It takes a configuration object and the set of enabled categories as input.
+// It returns the report of the run, or an error if the file list cannot be built or a file cannot be read.
+func collectReport(config *Config, categories categorySet) (*report, error) {
+	start := time.Now()
+
+	// Get the current working directory
+	cwd, err := os.Getwd()
+	// If an error occurs, return it
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	// Open the files to process: the working directory, or the files tracked by git in it
+	fsys, err := openSourceFS(cwd)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := fsys.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Build a list of files to process based on the configuration and the current working directory
+	filesToProcess, err := buildFileList(config, fsys, categories)
+	// If an error occurs, return it
+	if err != nil {
+		return nil, fmt.Errorf("failed to build file list: %w", err)
+	}
+
+	// Count the lines of every file, keeping the kind of every line to attribute them to their authors
+	var files []fileResult
+	var kinds [][]lineKind
+	withBlame := reportBy == byAuthor || reportBy == byEmailDomain
+	for _, file := range filesToProcess {
+		// If the language is not supported, skip the file
+		if file.Language == "" {
+			continue
+		}
+
+		// Read the content of the file
+		content, err := fs.ReadFile(fsys, file.Path)
+		// If an error occurs, return it
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+
+		files = append(files, fileResult{
+			Path:      file.Path,
+			Language:  file.Language,
+			Category:  file.Category,
+			lineStats: countLines(content, file.Comment),
+			Bytes:     int64(len(content)),
+			Generated: isGenerated(content),
+		})
+		if withBlame {
+			_, fileKinds := classifyLines(content, file.Comment)
+			kinds = append(kinds, fileKinds)
+		}
+	}
+
+	// Attribute the lines to their authors, or the files to their code owners
+	switch reportBy {
+	case byAuthor, byEmailDomain:
+		if err := attributeLines(fsys, cwd, files, kinds); err != nil {
+			return nil, err
+		}
+	case byOwner:
+		if err := assignCodeOwners(fsys, cwd, files); err != nil {
+			return nil, err
+		}
+	}
+
+	r := newReport(files)
+	// Record the enabled categories in their display order
+	for _, name := range categoryNames(config) {
+		if categories[name] {
+			r.Categories = append(r.Categories, name)
+		}
+	}
+	r.ConfigHash = configHash(config, categories)
+	// Record the commit the files were read from
+	if tree, ok := fsys.(*treeFS); ok {
+		r.Revision = tree.commit.Hash.String()
+	}
+	r.Elapsed = time.Since(start)
+	return r, nil
+}
+
+// newReport is a function that builds a report from the results of the processed files.
+// It sorts the files by path and computes the per-language aggregates and the totals.
+func newReport(files []fileResult) *report {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	r := &report{Files: files}
+	r.Languages, r.Total = summarizeFiles(files)
+	r.Owners = summarizeOwners(files)
+	return r
+}
+
+// summarizeFiles is a function that aggregates file results per language.
+// It returns the per-language aggregates, sorted by language name and category, and the totals.
+func summarizeFiles(files []fileResult) ([]languageSummary, languageSummary) {
+	var total languageSummary
+	byLanguage := make(map[[2]string]*languageSummary)
+	for _, file := range files {
+		key := [2]string{file.Language, file.Category}
+		summary, ok := byLanguage[key]
+		if !ok {
+			summary = &languageSummary{Language: file.Language, Category: file.Category}
+			byLanguage[key] = summary
+		}
+		summary.addFile(file)
+		total.addFile(file)
+	}
+
+	languages := make([]languageSummary, 0, len(byLanguage))
+	for _, summary := range byLanguage {
+		languages = append(languages, *summary)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Language != languages[j].Language {
+			return languages[i].Language < languages[j].Language
+		}
+		return languages[i].Category < languages[j].Category
+	})
+	return languages, total
+}
+
+// configHash is a function that returns a hash identifying the effective configuration of a run.
+// Two runs with the same configuration and the same enabled categories have the same hash.
+func configHash(config *Config, categories categorySet) string {
+	// Profiles are already applied to the configuration, and their filters are not processed
+	effective := *config
+	effective.Profiles = nil
+
+	// encoding/json sorts map keys, so the encoding is deterministic
+	data, err := json.Marshal(struct {
+		Config     *Config     `json:"config"`
+		Categories categorySet `json:"categories"`
+	}{&effective, categories})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Granularities of the rows of tabular reports, selected with the --by flag.
+const (
+	byFile        = "file"
+	byLanguage    = "language"
+	byDirectory   = "directory"
+	byAuthor      = "author"
+	byEmailDomain = "email-domain"
+	byOwner       = "owner"
+)
+
+// granularities lists the values accepted by the --by flag.
+var granularities = []string{byFile, byLanguage, byDirectory, byAuthor, byEmailDomain, byOwner}
+
+// reportRow is one row of a tabular report: a file, a language, a language within a directory, or the lines of a language attributed to an owner.
+type reportRow struct {
+	// Path is the path of the file or of the directory. It is empty for language and owner rows, but for the files without code owner.
+	Path string
+
+	// Owner is the author, the email domain or the code owner of owner rows.
+	Owner string
+
+	// Parent is the path of the parent directory of directory rows, empty for the root directory ".",
+	// and Depth the depth of the directory below the root directory, 0 for the root directory.
+	Parent string
+	Depth  int
+
+	// languageSummary holds the language of the row and its aggregated counts.
+	languageSummary
+}
+
+// rows returns the rows of the report at the given granularity.
+// File rows are sorted by path, language rows by language name, and owner rows by owner and language name.
+// Directory rows follow the directory tree, see dirTree: every directory has a row per language holding the totals of every file
+// below it, like the other formats, parents before their subdirectories. The root directory is ".", and a maximum depth greater
+// than 0 leaves out the directories deeper than it.
+// With code owners, the files without owner are listed one by one after the owners, with their path and the unowned owner.
+func (r *report) rows(by string, maxDepth int) ([]reportRow, error) {
+	var rows []reportRow
+	switch by {
+	case byFile:
+		for _, file := range r.Files {
+			row := reportRow{Path: file.Path}
+			row.Language, row.Category = file.Language, file.Category
+			row.addFile(file)
+			rows = append(rows, row)
+		}
+	case byLanguage:
+		for _, lang := range r.Languages {
+			rows = append(rows, reportRow{languageSummary: lang})
+		}
+	case byDirectory:
+		r.dirTree().walk(maxDepth, func(dir *dirNode, depth int) {
+			parent := ""
+			if dir.Path != "." {
+				parent = path.Dir(dir.Path)
+			}
+			for _, lang := range dir.Languages {
+				rows = append(rows, reportRow{Path: dir.Path, Parent: parent, Depth: depth, languageSummary: lang})
+			}
+		})
+	case byAuthor, byEmailDomain, byOwner:
+		for _, owner := range r.Owners {
+			if by == byOwner && owner.Owner == unowned {
+				continue
+			}
+			rows = append(rows, reportRow{Owner: owner.Owner, languageSummary: owner.languageSummary})
+		}
+		if by == byOwner {
+			for _, file := range r.unownedFiles() {
+				row := reportRow{Path: file.Path, Owner: unowned}
+				row.Language, row.Category = file.Language, file.Category
+				row.addFile(file)
+				rows = append(rows, row)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown granularity %q, expected one of %s", by, strings.Join(granularities, ", "))
+	}
+	return rows, nil
+}
+
+// generatedMarkers are the phrases marking generated files, matched case-insensitively in the first lines of a file.
+var generatedMarkers = []string{
+	"code generated",
+	"do not edit",
+	"@generated",
+	"automatically generated",
+	"auto-generated",
+	"autogenerated",
+}
+
+// generatedHeaderLines is the number of lines at the start of a file searched for generated markers.
+const generatedHeaderLines = 10
+
+// isGenerated is a function that checks whether the content of a file marks it as generated code.
+// Generators conventionally put such a marker in a comment at the top of the file.
+func isGenerated(content []byte) bool {
+	// Only look at the header of the file
+	header := content
+	for i, offset := 0, 0; i < generatedHeaderLines; i++ {
+		next := bytes.IndexByte(header[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+		if i == generatedHeaderLines-1 {
+			header = header[:offset]
+		}
+	}
+
+	lowerHeader := strings.ToLower(string(header))
+	for _, marker := range generatedMarkers {
+		if strings.Contains(lowerHeader, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,38 +2,61 @@
 package cmd
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// version is the version of locc, set at build time with -ldflags "-X github.com/zewebdev1337/locc/cmd.version=..."
+var version = "dev"
+
 var (
-	outputFile      string
+	outputFiles     []string
+	outputFormat    string
+	reportBy        string
+	reportColumns   []string
+	topFiles        int
+	sortKey         string
+	sortReverse     bool
+	minLines        int
+	metricLabels    []string
+	gitMode         bool
+	submoduleMode   string
+	revision        string
+	changedMode     bool
+	changedBase     string
+	treeDepth       int
 	configFile      string
 	enableStores    bool
 	enableDocuments bool
 	initLocalConfig bool
 	verbose         bool
+	profileName     string
+	// enableCategory and disableCategory list the categories enabled and disabled on the command line.
+	enableCategory  []string
+	disableCategory []string
 )
 
 // TODO: Fix/Define include behavior
 // TODO:➚ Complimentary to ↑, review config file structure and modify if necessary
 
 var rootCmd = &cobra.Command{
-	Use:   "locc",
-	Short: "Count lines of code in a project",
+	Use:     "locc",
+	Version: version,
+	Short:   "Count lines of code in a project",
 	Long: `locc (Lines of Code Counter) is a tool that scans the current directory and its subdirectories for code files,
-counts the number of non-empty lines in each file, and outputs the result.
+counts the number of blank, comment and code lines in each file, and outputs the result.
 
 It uses a configuration system that combines global and local settings:
-- Global configuration: Stored in ~/.locc.yaml
-- Local configuration: Defaults to ./.locc.yaml but can be specified with the --config flag
+- Global configuration: Stored in ~/.locc.yaml (or ~/.locc.json, ~/.locc.toml)
+- Local configuration: Defaults to ./.locc.yaml (or ./.locc.json, ./.locc.toml) but can be specified with the --config flag
+- Per-directory configuration: A .locc.yaml in any subdirectory applies to that subtree, layered on its parent's
 
 Features:
 - Language detection based on file extensions
@@ -43,12 +66,14 @@ Features:
 - Verbose output option
 
 Configuration:
-The configuration file should be in YAML format and can include:
+The configuration file should be in YAML, JSON or TOML format (chosen by its extension) and can include:
 - languages: Map of language configurations (extensions and comment syntax)
 - stores: Map of data store configurations (extensions and comment syntax)
 - documents: Map of document/plain text configurations (extensions and comment syntax)
 - exclusions: Map of file/directory exclusions (global and language-specific)
 - max_file_size: Maximum file size to process (in bytes)
+- categories: Map of custom categories (enabled by default or not, with their own languages)
+- profiles: Map of named profiles overriding filters and settings, selected with the --profile flag
 
 For more detailed information, please refer to the documentation.`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -59,19 +84,12 @@ For more detailed information, please refer to the documentation.`,
 			}
 			return // Exit after initialization
 		}
-		globalConfig, err := loadGlobalConfig()
-		if err != nil {
-			log.Fatal(err)
-		}
-		localConfig, err := loadLocalConfig(configFile)
+		config, categories, err := loadRunConfig(cmd)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		config := mergeConfigs(globalConfig, localConfig)
-		processFilters(config)
-
-		err = countLinesOfCode(config, enableStores, enableDocuments)
+		err = countLinesOfCode(config, categories)
 		if err != nil {
 			fmt.Println("Error:", err)
 			os.Exit(1)
@@ -79,6 +97,91 @@ For more detailed information, please refer to the documentation.`,
 	},
 }
 
+// loadRunConfig is a function that loads the configuration of a run, shared by the commands counting lines.
+// It merges the global and local configuration files, applies the selected profile and the overrides of the
+// environment and the command line, and resolves the enabled categories.
+// It returns the effective configuration and the set of enabled categories, or an error if any step fails.
+func loadRunConfig(cmd *cobra.Command) (*Config, categorySet, error) {
+	run, err := loadGlobalRunConfig(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	localConfig, err := loadLocalConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run.layer(localConfig)
+}
+
+// runConfig holds the parts of the configuration of a run that are the same for every tree it counts:
+// the global configuration, and the command whose profile settings, overrides and category flags apply on top of the local configuration.
+// Commands counting several revisions, like diff and history, layer it with the local configuration of each of them.
+type runConfig struct {
+	cmd    *cobra.Command
+	global *Config
+}
+
+// loadGlobalRunConfig is a function that loads the global configuration of a run, once the environment selected the configuration file and the profile.
+func loadGlobalRunConfig(cmd *cobra.Command) (*runConfig, error) {
+	applyEnvSettings(cmd)
+	globalConfig, err := loadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &runConfig{cmd: cmd, global: globalConfig}, nil
+}
+
+// layer returns the effective configuration and the set of enabled categories of a tree with a local configuration, nil if it has none.
+// The local configuration is merged into a copy of the global configuration, so that the run can layer the local configuration of several trees.
+func (r *runConfig) layer(localConfig *Config) (*Config, categorySet, error) {
+	config := mergeConfigs(cloneConfig(r.global), localConfig)
+	profile, err := applyProfile(config, profileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyProfileSettings(r.cmd, profile)
+	processFilters(config)
+
+	// Apply the overrides of the environment and the command line on top of every other layer
+	err = applyConfigOverrides(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	enable, disable := categoryToggles(r.cmd)
+	categories, err := resolveCategories(config, profile, enable, disable)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, categories, nil
+}
+
+// applyProfileSettings is a function that applies the command-line settings of a profile.
+// Settings given explicitly on the command line take precedence over the ones of the profile.
+func applyProfileSettings(cmd *cobra.Command, profile *ProfileConfig) {
+	// No profile selected, keep the command-line settings
+	if profile == nil {
+		return
+	}
+
+	flags := cmd.Flags()
+	if profile.Data != nil && !flags.Changed("data") {
+		enableStores = *profile.Data
+	}
+	if profile.Docs != nil && !flags.Changed("docs") {
+		enableDocuments = *profile.Docs
+	}
+	if profile.Output != "" && !flags.Changed("output") {
+		outputFiles = []string{profile.Output}
+	}
+	if profile.Format != "" && !flags.Changed("format") {
+		outputFormat = profile.Format
+	}
+	if profile.Verbose != nil && !flags.Changed("verbose") {
+		verbose = *profile.Verbose
+	}
+}
+
 // Execute is the entry point for the locc tool.
 // It calls the Execute method of the rootCmd object, which starts the command line interface.
 // If an error occurs, it prints the error message and exits with a non-zero status.
@@ -88,40 +191,116 @@ func Execute() {
 	}
 }
 
-// buildFileList is a function that constructs a list of files to process based on the configuration and the current working directory.
-// It takes a configuration object and the root directory as input.
-// It returns a slice of strings containing the paths of the files to process and an error if one occurs.
-func buildFileList(config *Config, rootDir string) ([]string, error) {
-	// Initialize a slice to store the paths of the files to process
-	var filesToProcess []string
+// sourceFile describes a file selected for processing by buildFileList.
+// Path is the slash-separated path of the file relative to the root directory,
+// Language and Comment are the language detected for the file and its comment syntax,
+// and Category is the category the language belongs to.
+type sourceFile struct {
+	Path     string
+	Language string
+	Category string
+	Comment  []string
+}
+
+// configScope pairs a directory with the configuration that applies to its subtree.
+// The root directory uses the merged global and local configuration, and every
+// subdirectory containing its own .locc.yaml gets a new scope layered on top of its parent's.
+// local holds the configuration file of the directory alone, whose directory exclusions are relative to the directory,
+// and parent the scope it is layered on, nil for the root directory.
+type configScope struct {
+	dir    string
+	config *Config
+	local  *Config
+	parent *configScope
+}
+
+// relPath returns the path of a file or directory relative to the scope directory.
+// Filters of nested configuration files are relative to the directory they live in,
+// the same way patterns in a .gitignore file are.
+func (s *configScope) relPath(name string) string {
+	if s.dir == "." {
+		return name
+	}
+	return strings.TrimPrefix(name, s.dir+"/")
+}
 
-	// Use the filepath.Walk function to traverse the directory tree rooted at rootDir
-	// For each file or directory encountered, the function calls the anonymous function provided as the second argument
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+// excludesDir returns whether a directory is excluded by the configuration of the scope or of one of its ancestors.
+// Every configuration matches the path of the directory relative to its own directory, so that the exclusions of a parent
+// keep working below a subdirectory with its own configuration file.
+func (s *configScope) excludesDir(name string) bool {
+	for scope := s; scope != nil; scope = scope.parent {
+		if shouldExcludeDir(scope.local, scope.relPath(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFileList is a function that constructs a list of files to process based on the configuration and a file system.
+// It takes a configuration object, the file system rooted at the directory to process and the set of enabled categories as input.
+// The file system is the working directory, or a view of it or of a git revision limited to the files tracked by git.
+// Subdirectories containing a configuration file get their own configuration, merged on top of the configuration of the parent directory,
+// which applies to that subtree only.
+// It returns a slice containing the files to process and an error if one occurs.
+func buildFileList(config *Config, fsys fs.FS, categories categorySet) ([]sourceFile, error) {
+	// Initialize a slice to store the files to process
+	var filesToProcess []sourceFile
+
+	// Map every visited directory to the configuration scope that applies to it
+	scopes := map[string]*configScope{
+		".": {dir: ".", config: config, local: config},
+	}
+
+	// Use the fs.WalkDir function to traverse the directory tree of the file system
+	// For each file or directory encountered, the function calls the anonymous function provided as the third argument
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
 		// If an error occurs, return it
 		if err != nil {
 			return err
 		}
 
-		// Get the relative path of the file or directory
-		relPath, _ := filepath.Rel(rootDir, path)
+		// The root directory already has its scope
+		if name == "." {
+			return nil
+		}
+
+		// Get the scope of the parent directory, the walk always visits it first
+		scope := scopes[path.Dir(name)]
+		// Get the path of the file or directory relative to the scope
+		relPath := scope.relPath(name)
 
 		// If the file is a directory
-		if info.IsDir() {
-			// Check if the directory should be excluded based on the configuration
-			if shouldExcludeDir(config, relPath) {
+		if entry.IsDir() {
+			// Check if the directory should be excluded based on the configuration of its scope and of the parent scopes
+			if scope.excludesDir(name) {
 				// If the directory should be excluded, skip it and its subdirectories
-				return filepath.SkipDir
+				return fs.SkipDir
+			}
+			// Open a new scope if the directory has its own configuration file
+			dirScope, err := loadDirScope(fsys, scope, name)
+			if err != nil {
+				return err
 			}
+			scopes[name] = dirScope
 			// If the directory should not be excluded, continue traversing it
 			return nil
 		}
 
 		// If the file is not a directory
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
 		// Check if the file should be included based on the configuration
-		if shouldIncludeFile(config, relPath, info, enableStores, enableDocuments) {
-			// If the file should be included, add its path to the slice of files to process
-			filesToProcess = append(filesToProcess, path)
+		if shouldIncludeFile(scope.config, fsys, name, relPath, info, categories) {
+			// If the file should be included, add it to the slice of files to process
+			lang, category, comment := detectLanguage(name, scope.config, categories)
+			filesToProcess = append(filesToProcess, sourceFile{
+				Path:     name,
+				Language: lang,
+				Category: category,
+				Comment:  comment,
+			})
 		}
 
 		// Continue traversing the directory tree
@@ -137,6 +316,32 @@ func buildFileList(config *Config, rootDir string) ([]string, error) {
 	return filesToProcess, nil
 }
 
+// loadDirScope is a function that returns the configuration scope of a directory.
+// If the directory contains a .locc.yaml, .locc.json or .locc.toml file, it is merged on top of a copy of the parent configuration
+// and a new scope rooted at the directory is returned. Otherwise the directory shares the scope of its parent.
+func loadDirScope(fsys fs.FS, parent *configScope, dir string) (*configScope, error) {
+	configPath, ok := findConfigFileFS(fsys, dir)
+	if !ok {
+		// No configuration file, the parent scope applies
+		return parent, nil
+	}
+
+	dirConfig, err := readConfigFS(fsys, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Layer the directory configuration on the parent configuration
+	processFilters(dirConfig)
+	config := layerConfigs(parent.config, dirConfig)
+	// Overrides from the environment and the command line still take precedence
+	if err := applyConfigOverrides(config); err != nil {
+		return nil, err
+	}
+
+	return &configScope{dir: dir, config: config, local: dirConfig, parent: parent}, nil
+}
+
 // shouldExcludeDir is a function that checks whether a directory should be excluded from the process based on the configuration.
 // It takes a configuration object and the relative path of the directory as input.
 // It returns a boolean value indicating whether the directory should be excluded.
@@ -165,38 +370,42 @@ func shouldExcludeDir(config *Config, relPath string) bool {
 	return false
 }
 
-func shouldIncludeFile(config *Config, relPath string, info os.FileInfo, enableStores, enableDocuments bool) bool {
+// shouldIncludeFile is a function that checks whether a file should be processed based on the configuration.
+// It takes a configuration object, the file system, the path of the file in it, its path relative to the configuration scope,
+// its file info and the set of enabled categories as input.
+// It returns a boolean value indicating whether the file should be included.
+func shouldIncludeFile(config *Config, fsys fs.FS, name, relPath string, info fs.FileInfo, categories categorySet) bool {
 	if config.MaxFileSize > 0 && info.Size() > config.MaxFileSize {
 		return false
 	}
 
-	fileName := filepath.Base(relPath)
-	lang, _ := detectLanguage(relPath, config, enableStores, enableDocuments)
+	fileName := path.Base(relPath)
+	lang, _, _ := detectLanguage(relPath, config, categories)
 
 	// Check global includes
 	if globalIncludes, ok := config.Includes["locc"]; ok {
-		if matchesFilter(globalIncludes, fileName, relPath) {
+		if matchesFilter(globalIncludes, fsys, fileName, name) {
 			return true
 		}
 	}
 
 	// Check language-specific includes
 	if includes, ok := config.Includes[lang]; ok {
-		if matchesFilter(includes, fileName, relPath) {
+		if matchesFilter(includes, fsys, fileName, name) {
 			return true
 		}
 	}
 
 	// Check global excludes
 	if globalExcludes, ok := config.Excludes["locc"]; ok {
-		if matchesFilter(globalExcludes, fileName, relPath) {
+		if matchesFilter(globalExcludes, fsys, fileName, name) {
 			return false
 		}
 	}
 
 	// Check language-specific excludes
 	if excludes, ok := config.Excludes[lang]; ok {
-		if matchesFilter(excludes, fileName, relPath) {
+		if matchesFilter(excludes, fsys, fileName, name) {
 			return false
 		}
 	}
@@ -204,14 +413,16 @@ func shouldIncludeFile(config *Config, relPath string, info os.FileInfo, enableS
 	return lang != ""
 }
 
-func matchesFilter(filter interface{}, fileName, relPath string) bool {
+// matchesFilter is a function that checks whether a file matches a processed filter.
+// Files listed with a wordlist only match when their content, read from the file system, contains one of the words.
+func matchesFilter(filter interface{}, fsys fs.FS, fileName, name string) bool {
 	switch v := filter.(type) {
 	case map[string][]string:
 		if wordlist, ok := v[fileName]; ok {
 			if len(wordlist) == 0 {
 				return true
 			}
-			content, err := os.ReadFile(relPath)
+			content, err := fs.ReadFile(fsys, name)
 			if err != nil {
 				return false
 			}
@@ -238,6 +449,14 @@ func matchesFilter(filter interface{}, fileName, relPath string) bool {
 func containsPath(exclusions interface{}, path string) bool {
 	// Switch on the type of the exclusions object.
 	switch v := exclusions.(type) {
+	// If the exclusions object is a processed filter, iterate over the keys of the map.
+	case map[string][]string:
+		for key := range v {
+			// If the path matches the exclusion pattern, return true.
+			if matchesExclusion(key, path) {
+				return true
+			}
+		}
 	// If the exclusions object is a map of exclusions for a specific language, iterate over the keys of the map.
 	case map[string]FileExclusion:
 		for key := range v {
@@ -288,161 +507,184 @@ func matchesExclusion(pattern, path string) bool {
 }
 
 // detectLanguage is a function that detects the language of a file based on its extension and the configuration.
-// It takes a file name and a configuration object as input.
-// It returns the language of the file and the comment syntax for that language.
-func detectLanguage(filename string, config *Config, enableStores, enableDocuments bool) (string, []string) {
-	// Get the extension of the file from its name and convert it to lower case.
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	// Iterate over the map of languages in the configuration.
-	for lang, langConfig := range config.Languages {
-		// For each language, iterate over the slice of extensions for that language.
-		for _, e := range langConfig.Extensions {
-			// If the extension of the file matches an extension for the language, return the language and the comment syntax for that language.
-			if e == ext {
-				return lang, langConfig.Comment
+// It takes a file name, a configuration object and the set of enabled categories as input.
+// Custom categories are checked first, in the order of their names, followed by languages, stores and documents.
+// A file matching a custom category is claimed by it, so it is only counted when that category is enabled,
+// while disabled built-in categories are skipped.
+// It returns the language of the file, the category of the language and the comment syntax for that language.
+func detectLanguage(filename string, config *Config, categories categorySet) (string, string, []string) {
+	// Check custom categories, enabled or not
+	for _, name := range customCategoryNames(config) {
+		if lang, langConfig, ok := findLanguage(config.Categories[name].Languages, filename); ok {
+			// If the category is disabled, the file is not processed
+			if !categories[name] {
+				return "", "", nil
 			}
+			return lang, name, langConfig.Comment
 		}
 	}
 
-	// Check stores if enabled
-	if enableStores {
-		for store, storeConfig := range config.Stores {
-			for _, e := range storeConfig.Extensions {
-				if e == ext {
-					return store, storeConfig.Comment
-				}
-			}
+	// Check the built-in categories if enabled
+	builtins := map[string]map[string]LanguageConfig{
+		categoryLanguages: config.Languages,
+		categoryStores:    config.Stores,
+		categoryDocuments: config.Documents,
+	}
+	for _, name := range builtinCategories {
+		if !categories[name] {
+			continue
+		}
+		if lang, langConfig, ok := findLanguage(builtins[name], filename); ok {
+			return lang, name, langConfig.Comment
 		}
 	}
 
-	// Check documents if enabled
-	if enableDocuments {
-		for doc, docConfig := range config.Documents {
-			for _, e := range docConfig.Extensions {
-				if e == ext {
-					return doc, docConfig.Comment
-				}
+	// If the extension of the file does not match any of the extensions in the configuration, return empty strings and nil to indicate that the language is not supported.
+	return "", "", nil
+}
+
+// findLanguage is a function that looks up the language of a file in a map of language configurations.
+// Extensions starting with a dot match the end of the file name, case-insensitively, so that extensions with several dots like '.env.example' work.
+// Other extensions, like 'Makefile' or 'Dockerfile', match the whole file name.
+// When several extensions match, like '.ts' and '.d.ts', the longest one wins, and languages sharing it are decided by name,
+// so that the result does not depend on the order of the map.
+// It returns the language, its configuration and whether a language was found.
+func findLanguage(languages map[string]LanguageConfig, filename string) (string, LanguageConfig, bool) {
+	// Get the name of the file and its lower case version.
+	base := filepath.Base(filename)
+	lowerBase := strings.ToLower(base)
+
+	// Iterate over the map of languages, keeping the best match.
+	found, longest := "", 0
+	for lang, langConfig := range languages {
+		// For each language, iterate over the slice of extensions for that language.
+		for _, e := range langConfig.Extensions {
+			if !(strings.HasPrefix(e, ".") && strings.HasSuffix(lowerBase, e) || e == base) {
+				continue
+			}
+			// Keep the language if its extension is longer than the best match, or as long with a smaller name.
+			if len(e) > longest || len(e) == longest && lang < found {
+				found, longest = lang, len(e)
 			}
 		}
 	}
 
-	// If the extension of the file does not match any of the extensions in the configuration, return an empty string and nil to indicate that the language is not supported.
-	return "", nil
+	// The file does not belong to any of the languages
+	if longest == 0 {
+		return "", LanguageConfig{}, false
+	}
+	return found, languages[found], true
 }
 
 // Counts the number of lines of code in a project based on the configuration.
-// It takes a configuration object and two boolean values indicating whether to enable stores and documents as input.
+// It takes a configuration object and the set of enabled categories as input.
+// It writes the report to every output given with --output, or to the standard output in the selected format if none is given.
 // It returns an error if one occurs.
-func countLinesOfCode(config *Config, enableStores, enableDocuments bool) error {
-	// Get the current working directory
-	cwd, err := os.Getwd()
-	// If an error occurs, return it
-	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+func countLinesOfCode(config *Config, categories categorySet) error {
+	// Check the options arranging the rows and the outputs before counting
+	if err := validateArrangement(); err != nil {
+		return err
 	}
-
-	// Build a list of files to process based on the configuration and the current working directory
-	filesToProcess, err := buildFileList(config, cwd)
-	// If an error occurs, return it
+	targets, err := parseOutputs(outputFiles, outputFormat)
 	if err != nil {
-		return fmt.Errorf("failed to build file list: %w", err)
-	}
-
-	// Initialize a variable to store the total number of lines of code
-	totalLines := 0
-	// Initialize a strings.Builder object to store the output
-	var output strings.Builder
-
-	// Iterate over the slice of files to process
-	for _, path := range filesToProcess {
-		// Get the relative path of the file
-		relPath, _ := filepath.Rel(cwd, path)
-		// Detect the language of the file based on its extension and the configuration
-		lang, _ := detectLanguage(path, config, enableStores, enableDocuments)
-		// If the language is not supported, skip the file
-		if lang == "" {
-			continue
-		}
+		return err
+	}
 
-		// Read the content of the file
-		content, err := os.ReadFile(path)
-		// If an error occurs, return it
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", path, err)
-		}
+	// Count only the lines changed relative to a base if requested
+	if changedMode {
+		return countChangedLines(config, categories, targets)
+	}
+	if changedBase != "" {
+		return fmt.Errorf("--base can only be used with --changed")
+	}
 
-		// Count the number of non-empty lines in the content
-		lineCount := countNonEmptyLines(content)
-		// Add the line count to the total number of lines of code
-		totalLines += lineCount
+	// Count the lines of every file selected by the configuration
+	r, err := collectReport(config, categories)
+	if err != nil {
+		return err
+	}
 
-		// If verbose output is enabled, print the file name, language, and line count
-		if verbose {
-			fmt.Printf("File: %s, Language: %s, Lines: %d\n", relPath, lang, lineCount)
+	// Status messages go to the standard error if a report is written to the standard output, so as not to corrupt it
+	status := os.Stdout
+	for _, target := range targets {
+		if target.path == "" {
+			status = os.Stderr
 		}
-
-		// Write the file name, language, and line count to the output
-		output.WriteString(fmt.Sprintf("%s,%s,%d\n", relPath, lang, lineCount))
 	}
 
-	// Print the total number of lines of code
-	fmt.Printf("Total lines of code: %d\n", totalLines)
-
-	// If an output file is specified, write the output to the file
-	if outputFile != "" {
-		err = os.WriteFile(outputFile, []byte(output.String()), 0644)
-		// If an error occurs, return it
+	// Write the report to every output, from the single scan
+	for _, target := range targets {
+		err = writeReport(target.format, target.path, r)
 		if err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
+			return err
 		}
+
 		// Print the name of the output file
-		fmt.Printf("Output written to %s\n", outputFile)
+		if target.path != "" {
+			fmt.Fprintf(status, "Output written to %s\n", target.path)
+		}
 	}
 
 	// If no error occurs, return nil
 	return nil
 }
 
-// Counts the number of non-empty lines in a byte slice.
-// It takes a byte slice containing the content of a file as input.
-// It returns an integer representing the number of non-empty lines in the content.
-func countNonEmptyLines(content []byte) int {
-	// Create a new scanner that reads from a new reader that reads from the content byte slice.
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	// Initialize a variable to store the number of non-empty lines.
-	lineCount := 0
-	// Use a for loop to iterate over the lines in the content.
-	for scanner.Scan() {
-		// Trim any whitespace from the current line and check if its length is greater than 0.
-		if len(strings.TrimSpace(scanner.Text())) > 0 {
-			// If the length is greater than 0, increment the line count.
-			lineCount++
-		}
-	}
-	// Return the line count.
-	return lineCount
-}
-
 // Registers command-line flags for the rootCmd object.
 func init() {
 	// If the flag is not provided, the output will be printed to the console.
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file name (optional)")
+	// The flag can be repeated as format=path to write several formats from a single scan, "-" standing for the console.
+	rootCmd.Flags().StringArrayVarP(&outputFiles, "output", "o", nil, "Output file name, or format=path (repeatable, - for the console)")
+	// Selects the output format.
+	// The text format prints a summary to the terminal, other formats write a document to the output file or the terminal.
+	rootCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format ("+strings.Join(formatNames(), ", ")+")")
+	// Selects the granularity and the columns of tabular output formats.
+	rootCmd.Flags().StringVar(&reportBy, "by", byFile, "Granularity of the report rows ("+strings.Join(granularities, ", ")+")")
+	// Limits the depth of the directories of the directory granularity, deeper directories are aggregated into their ancestors.
+	rootCmd.Flags().IntVar(&treeDepth, "depth", 0, "Maximum depth of the directories with --by directory (0 for no limit)")
+	rootCmd.Flags().StringSliceVar(&reportColumns, "columns", nil, "Comma-separated columns of the csv and tsv formats")
+	// Selects and orders the rows of the report, in every output format but the cloc and tokei compatible ones.
+	// --top applies to files and directories, and adds a table of the largest files to the markdown format.
+	rootCmd.Flags().StringVar(&sortKey, "sort", "", "Sort the rows by "+strings.Join(sortKeys, ", ")+" (numbers largest first)")
+	rootCmd.Flags().BoolVar(&sortReverse, "reverse", false, "Reverse the order of the rows")
+	rootCmd.Flags().IntVar(&topFiles, "top", 0, "Keep only the first N files or directories (0 for all)")
+	rootCmd.Flags().IntVar(&minLines, "min-lines", 0, "Hide the files, languages and directories with fewer lines of code")
+	// Adds labels to the samples of the openmetrics format.
+	rootCmd.Flags().StringArrayVar(&metricLabels, "label", nil, "Label added to the samples of the openmetrics format, as name=value (repeatable)")
+	// The configuration flags are persistent, so the subcommands counting lines accept them as well.
 	// Allows the user to specify a local configuration file.
 	// If the flag is not provided, the tool will use the default local configuration file (.locc.yaml).
-	rootCmd.Flags().StringVarP(&configFile, "config", "c", "", "Local configuration file (optional)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Local configuration file (optional)")
 	// Enables the processing of data stores (JSON, YAML, etc.).
 	// If the flag is not provided, the tool will not process data stores.
-	rootCmd.Flags().BoolVar(&enableStores, "data", false, "Enable processing of data stores (JSON, YAML, etc.)")
+	rootCmd.PersistentFlags().BoolVar(&enableStores, "data", false, "Enable processing of data stores (JSON, YAML, etc.)")
 	// Enables the processing of documents (plain text, Markdown, etc.).
 	// If the flag is not provided, the tool will not process documents.
-	rootCmd.Flags().BoolVar(&enableDocuments, "docs", false, "Enable processing of documents (plain text, Markdown, etc.)")
+	rootCmd.PersistentFlags().BoolVar(&enableDocuments, "docs", false, "Enable processing of documents (plain text, Markdown, etc.)")
 	// Initializes a local configuration file.
 	// If the flag is provided, the tool will create a default local configuration file (.locc.yaml) in the current directory and exit.
 	rootCmd.Flags().BoolVar(&initLocalConfig, "init", false, "Initialize a local configuration file")
 	// Enables verbose output.
 	// If the flag is provided, the tool will print the number of lines of code for each file it processes in addition to the total lines of code.
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	// Selects a profile from the configuration.
+	// If the flag is provided, the overrides of the named profile are applied on top of the merged configuration.
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", "", "Apply the named profile from the configuration (optional)")
+	// Enables or disables categories by name, including the built-in stores and documents categories.
+	// If the flags are not provided, categories follow their 'enabled' key in the configuration.
+	rootCmd.PersistentFlags().StringSliceVar(&enableCategory, "category", nil, "Enable processing of the named categories")
+	rootCmd.PersistentFlags().StringSliceVar(&disableCategory, "no-category", nil, "Disable processing of the named categories")
+	// Restricts the processed files to the files tracked by git, read from the git index.
+	// Submodules are skipped unless --submodules recurse is given.
+	rootCmd.PersistentFlags().BoolVar(&gitMode, "git", false, "Process only the files tracked by git")
+	rootCmd.PersistentFlags().StringVar(&revision, "rev", "", "Process the files of a git commit, tag or branch, read from the object database without checking it out")
+	rootCmd.PersistentFlags().StringVar(&submoduleMode, "submodules", submodulesSkip, "Submodules with --git and --rev: "+submodulesSkip+" or "+submodulesRecurse)
+	// Counts only the lines added and removed in the files changed relative to HEAD, or to the merge base with the --base revision,
+	// like a pull request does. Staged and unstaged changes count too.
+	rootCmd.Flags().BoolVar(&changedMode, "changed", false, "Count only the lines added and removed in the files changed relative to HEAD or --base")
+	rootCmd.Flags().StringVar(&changedBase, "base", "", "Revision the changes of --changed are relative to, from its merge base with HEAD")
+	// Overrides configuration keys, on top of the global, local and per-directory configuration files.
+	// Every override can also be given with a LOCC_* environment variable, which the flag takes precedence over.
+	registerOverrideFlags(rootCmd.PersistentFlags())
 }
 
 func runInit(filename string) error {
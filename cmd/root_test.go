@@ -0,0 +1,64 @@
+// cmd/root_test.go
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuildFileListExcludesDirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":                   {Data: []byte("package main\n")},
+		"internal/a.go":             {Data: []byte("package internal\n")},
+		"vendor/lib/lib.go":         {Data: []byte("package lib\n")},
+		"node_modules/pkg/index.js": {Data: []byte("module.exports = {}\n")},
+		"build/gen.go":              {Data: []byte("package gen\n")},
+		"src/vendor/b.go":           {Data: []byte("package vendor\n")},
+		"tools/gen/c.go":            {Data: []byte("package gen\n")},
+		"tools/gen.go":              {Data: []byte("package tools\n")},
+	}
+	tests := []struct {
+		name     string
+		excludes map[string]interface{}
+		paths    []string
+	}{
+		{
+			// The directories excluded by the default configuration are skipped, their patterns are matched from the root so src/vendor is kept
+			name:  "default configuration",
+			paths: []string{"internal/a.go", "main.go", "src/vendor/b.go", "tools/gen/c.go", "tools/gen.go"},
+		},
+		{
+			name:     "language exclusion",
+			excludes: map[string]interface{}{"go": []interface{}{"tools/gen/"}},
+			paths:    []string{"internal/a.go", "main.go", "src/vendor/b.go", "tools/gen.go"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := retrieveDefaultConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			initConfigMaps(config)
+			for lang, exclusions := range test.excludes {
+				config.Excludes[lang] = exclusions
+			}
+			processFilters(config)
+
+			files, err := buildFileList(config, fsys, categorySet{categoryLanguages: true})
+			if err != nil {
+				t.Fatalf("buildFileList() error = %v", err)
+			}
+			paths := []string{}
+			for _, file := range files {
+				if file.Language != "" {
+					paths = append(paths, file.Path)
+				}
+			}
+			if !reflect.DeepEqual(paths, test.paths) {
+				t.Errorf("buildFileList() = %q, want %q", paths, test.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// cmd/table.go
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// minTruncatedWidth is the narrowest a truncated table column gets, however narrow the terminal is.
+const minTruncatedWidth = 8
+
+// textColumn describes a column of a text table.
+type textColumn struct {
+	// header is the title of the column.
+	header string
+
+	// right aligns the column to the right, which is used for numbers.
+	right bool
+}
+
+// textTable is an aligned table written to the terminal or to a text file.
+// The first column holds names and is truncated when the table does not fit in the available width,
+// the other columns hold numbers and are never truncated.
+type textTable struct {
+	columns []textColumn
+	rows    [][]string
+	footer  []string
+}
+
+// addRow adds a row to the table.
+func (t *textTable) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// setFooter sets the row written below the other rows, such as the totals.
+func (t *textTable) setFooter(cells ...string) {
+	t.footer = cells
+}
+
+// render writes the table to w.
+// A width greater than 0 limits the width of the table by truncating the first column,
+// and a true styler writes the header and the footer in bold.
+func (t *textTable) render(w io.Writer, width int, color styler) error {
+	// Compute the width of every column
+	widths := make([]int, len(t.columns))
+	measure := func(cells []string) {
+		for i, cell := range cells {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	headers := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		headers[i] = column.header
+	}
+	measure(headers)
+	for _, row := range t.rows {
+		measure(row)
+	}
+	if t.footer != nil {
+		measure(t.footer)
+	}
+
+	// Every cell is preceded by a space, and cells are separated by two spaces
+	total := 1 + 2*(len(widths)-1)
+	for _, w := range widths {
+		total += w
+	}
+	// Truncate the first column if the table is too wide
+	if width > 0 && total > width {
+		widths[0] = max(minTruncatedWidth, widths[0]-(total-width))
+		total = 1 + 2*(len(widths)-1)
+		for _, w := range widths {
+			total += w
+		}
+	}
+
+	line := strings.Repeat("-", total)
+	format := func(cells []string) string {
+		var b strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				b.WriteString("  ")
+			} else {
+				b.WriteString(" ")
+			}
+			cell = truncate(cell, widths[i])
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if t.columns[i].right {
+				b.WriteString(padding + cell)
+			} else if i < len(cells)-1 {
+				b.WriteString(cell + padding)
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		return b.String()
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, line)
+	fmt.Fprintln(&b, color.style(ansiBold, format(headers)))
+	fmt.Fprintln(&b, line)
+	for _, row := range t.rows {
+		fmt.Fprintln(&b, format(row))
+	}
+	if t.footer != nil {
+		fmt.Fprintln(&b, line)
+		fmt.Fprintln(&b, color.style(ansiBold, format(t.footer)))
+	}
+	fmt.Fprintln(&b, line)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// truncate is a function that shortens text to the given number of characters, marking the cut with '~'.
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	if width < 1 {
+		return ""
+	}
+	return string(runes[:width-1]) + "~"
+}
@@ -0,0 +1,61 @@
+// cmd/terminal.go
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// defaultTerminalWidth is the width assumed when the width of the terminal cannot be determined.
+const defaultTerminalWidth = 80
+
+// isTerminal is a function that checks whether a writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// terminalWidth is a function that returns the width available to write to w.
+// It is the width of the terminal when w is one, otherwise the COLUMNS environment variable or defaultTerminalWidth.
+// Output that is not written to a terminal is not limited, 0 is returned for it.
+func terminalWidth(w io.Writer) int {
+	if !isTerminal(w) {
+		return 0
+	}
+	if width, _, err := term.GetSize(int(w.(*os.File).Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// useColor is a function that checks whether output written to w can use colors.
+// Colors are only used on terminals, and never when the NO_COLOR environment variable is set to a non-empty value (https://no-color.org).
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// ANSI escape sequences used to style terminal output.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+)
+
+// styler applies ANSI styles to text, or leaves it untouched when colors are disabled.
+type styler bool
+
+// style wraps text in the given ANSI style if colors are enabled.
+func (s styler) style(style, text string) string {
+	if !s || text == "" {
+		return text
+	}
+	return style + text + ansiReset
+}
@@ -0,0 +1,84 @@
+// cmd/tree.go
+package cmd
+
+import (
+	"path"
+	"sort"
+)
+
+// dirNode is a directory of the tree of the processed files, with the totals of every file below it.
+type dirNode struct {
+	// Name is the name of the directory, and Path its path relative to the root directory, "." for the root.
+	Name string
+	Path string
+
+	// Dirs holds the subdirectories, sorted by name, and Files the files directly in the directory, sorted by path.
+	Dirs  []*dirNode
+	Files []fileResult
+
+	// Total holds the aggregate of every file below the directory, and Languages its per-language aggregates.
+	Total     languageSummary
+	Languages []languageSummary
+}
+
+// buildDirTree is a function that builds the directory tree of the files of a report.
+// Only directories containing processed files, directly or below them, are part of the tree.
+func buildDirTree(files []fileResult) *dirNode {
+	root := &dirNode{Name: ".", Path: "."}
+	nodes := map[string]*dirNode{".": root}
+
+	// node returns the node of a directory, creating it and its parents as needed
+	var node func(dir string) *dirNode
+	node = func(dir string) *dirNode {
+		if n, ok := nodes[dir]; ok {
+			return n
+		}
+		parent := node(path.Dir(dir))
+		n := &dirNode{Name: path.Base(dir), Path: dir}
+		parent.Dirs = append(parent.Dirs, n)
+		nodes[dir] = n
+		return n
+	}
+
+	// Attach every file to its directory
+	below := make(map[string][]fileResult)
+	for _, file := range files {
+		dir := path.Dir(file.Path)
+		n := node(dir)
+		n.Files = append(n.Files, file)
+
+		// Record the file in the directory and every parent directory, for the totals
+		for {
+			below[dir] = append(below[dir], file)
+			if dir == "." {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+	}
+
+	// Compute the totals and sort the subdirectories
+	for dir, n := range nodes {
+		n.Languages, n.Total = summarizeFiles(below[dir])
+		sort.Slice(n.Dirs, func(i, j int) bool {
+			return n.Dirs[i].Name < n.Dirs[j].Name
+		})
+	}
+	return root
+}
+
+// walk calls fn for the directory and every directory below it, parents before their subdirectories.
+// A maximum depth greater than 0 skips the directories deeper than it below the directory, the directory itself being at depth 0.
+func (n *dirNode) walk(maxDepth int, fn func(dir *dirNode, depth int)) {
+	var visit func(dir *dirNode, depth int)
+	visit = func(dir *dirNode, depth int) {
+		fn(dir, depth)
+		if maxDepth > 0 && depth >= maxDepth {
+			return
+		}
+		for _, child := range dir.Dirs {
+			visit(child, depth+1)
+		}
+	}
+	visit(n, 0)
+}